@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	goast "go/ast"
+)
+
+// ChangeClass determines the compatibility of an API change.
+type ChangeClass int
+
+const (
+	// NonBreaking is a change that does not affect the exported API (e.g. changing unexported struct fields).
+	NonBreaking ChangeClass = iota
+	// Additive is a backward-compatible change that extends the exported API (e.g. adding a function).
+	Additive
+	// Breaking is a change that can break the code using the exported API (e.g. removing a function).
+	Breaking
+)
+
+// String implements the fmt.Stringer interface.
+func (c ChangeClass) String() string {
+	switch c {
+	case NonBreaking:
+		return "non-breaking"
+	case Additive:
+		return "additive"
+	case Breaking:
+		return "breaking"
+	default:
+		return "unknown"
+	}
+}
+
+// APIChange is a change to an exported type, function, or method between two parse results.
+type APIChange struct {
+	ImportPath string
+	// Name is the name of the type or function, or the receiver type name and the method name for methods (e.g. Service.Lookup).
+	Name string
+	// Change is either added, removed, or changed.
+	Change string
+	Class  ChangeClass
+}
+
+// apiDecl is an exported declaration compared between two parse results.
+type apiDecl struct {
+	kind string
+	// signature is the Go source representation of the declaration.
+	signature string
+	// fields are the exported fields of a struct type mapped to their Go source representations.
+	fields map[string]string
+	// funcType is the signature of a function or a function type, compared ignoring parameter names.
+	funcType *goast.FuncType
+	// methods are the methods of an interface type, compared ignoring parameter names.
+	methods map[string]*goast.FuncType
+}
+
+// DiffAPI compares the exported types, functions, and methods of two parse results.
+// Packages are matched by their import paths, and external test packages are ignored.
+// Adding declarations is additive, and removing declarations or changing their signatures is breaking.
+// Signatures are compared using SignatureEqual, so renaming parameters and results is not a change.
+// For struct types, adding exported fields is additive and changing only unexported fields is non-breaking.
+// The changes are sorted by import path and name.
+func DiffAPI(old, new *Result) []APIChange {
+	oldDecls, newDecls := old.apiDecls(), new.apiDecls()
+	changes := []APIChange{}
+
+	for key, od := range oldDecls {
+		importPath, name := splitAPIKey(key)
+
+		nd, ok := newDecls[key]
+		if !ok {
+			changes = append(changes, APIChange{ImportPath: importPath, Name: name, Change: "removed", Class: Breaking})
+		} else if class, changed := diffAPIDecl(od, nd); changed {
+			changes = append(changes, APIChange{ImportPath: importPath, Name: name, Change: "changed", Class: class})
+		}
+	}
+
+	for key := range newDecls {
+		if _, ok := oldDecls[key]; !ok {
+			importPath, name := splitAPIKey(key)
+			changes = append(changes, APIChange{ImportPath: importPath, Name: name, Change: "added", Class: Additive})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ImportPath != changes[j].ImportPath {
+			return changes[i].ImportPath < changes[j].ImportPath
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// apiDecls returns the exported declarations of all packages keyed by their import paths and names.
+func (r *Result) apiDecls() map[string]*apiDecl {
+	decls := make(map[string]*apiDecl)
+
+	for _, rp := range r.packages {
+		if strings.HasSuffix(rp.Name, "_test") {
+			continue
+		}
+
+		for _, rt := range rp.Types {
+			if IsExported(rt.Name) {
+				decls[rp.ImportPath+" "+rt.Name] = newAPIType(rt)
+			}
+		}
+
+		for _, rf := range rp.Funcs {
+			name := rf.Name
+			if rf.RecvType != "" {
+				recvTypeName := strings.TrimPrefix(rf.RecvType, "*")
+				if i := strings.Index(recvTypeName, "["); i >= 0 {
+					recvTypeName = recvTypeName[:i]
+				}
+
+				// Exported methods of unexported types are not part of the API
+				if !IsExported(recvTypeName) {
+					continue
+				}
+				name = recvTypeName + "." + rf.Name
+			}
+
+			if IsExported(rf.Name) {
+				decls[rp.ImportPath+" "+name] = &apiDecl{
+					kind:      "func",
+					signature: rf.RecvType,
+					funcType:  rf.ft,
+				}
+			}
+		}
+	}
+
+	return decls
+}
+
+func newAPIType(rt *resultType) *apiDecl {
+	d := &apiDecl{
+		kind: rt.Kind,
+	}
+
+	// Typed nil expressions are not rendered
+	switch v := rt.expr.(type) {
+	case *goast.StructType:
+		if v == nil {
+			return d
+		}
+
+		d.signature = exprString(v)
+		d.fields = make(map[string]string)
+		for _, field := range v.Fields.List {
+			typ := exprString(field.Type)
+			if len(field.Names) == 0 {
+				// Embedded fields are named after their types
+				if name := InferName(field.Type); IsExported(name) {
+					d.fields[name] = typ
+				}
+			}
+
+			for _, name := range field.Names {
+				if IsExported(name.Name) {
+					d.fields[name.Name] = typ
+				}
+			}
+		}
+
+	case *goast.InterfaceType:
+		if v == nil {
+			return d
+		}
+
+		// Embedded interfaces and type constraints are compared textually
+		d.methods = make(map[string]*goast.FuncType)
+		embedded := []string{}
+		if v.Methods != nil {
+			for _, field := range v.Methods.List {
+				ft, ok := field.Type.(*goast.FuncType)
+				if !ok {
+					embedded = append(embedded, exprString(field.Type))
+					continue
+				}
+
+				for _, name := range field.Names {
+					d.methods[name.Name] = ft
+				}
+			}
+		}
+		d.signature = strings.Join(embedded, "; ")
+
+	case *goast.FuncType:
+		d.funcType = v
+
+	case goast.Expr:
+		d.signature = exprString(v)
+	}
+
+	return d
+}
+
+// diffAPIDecl compares two versions of a declaration and classifies the change if there is any.
+func diffAPIDecl(old, new *apiDecl) (ChangeClass, bool) {
+	if old.kind != new.kind {
+		return Breaking, true
+	}
+
+	if old.funcType != nil || new.funcType != nil {
+		return Breaking, old.signature != new.signature || !SignatureEqual(old.funcType, new.funcType)
+	}
+
+	if old.methods != nil || new.methods != nil {
+		return Breaking, old.signature != new.signature || !methodsEqual(old.methods, new.methods)
+	}
+
+	if old.fields == nil || new.fields == nil {
+		return Breaking, old.signature != new.signature
+	}
+
+	for name, typ := range old.fields {
+		if newTyp, ok := new.fields[name]; !ok || newTyp != typ {
+			return Breaking, true
+		}
+	}
+
+	if len(new.fields) > len(old.fields) {
+		return Additive, true
+	}
+
+	return NonBreaking, old.signature != new.signature
+}
+
+func methodsEqual(old, new map[string]*goast.FuncType) bool {
+	if len(old) != len(new) {
+		return false
+	}
+
+	for name, ft := range old {
+		if !SignatureEqual(ft, new[name]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitAPIKey(key string) (string, string) {
+	importPath, name, _ := strings.Cut(key, " ")
+	return importPath, name
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeClass_String(t *testing.T) {
+	assert.Equal(t, "non-breaking", NonBreaking.String())
+	assert.Equal(t, "additive", Additive.String())
+	assert.Equal(t, "breaking", Breaking.String())
+	assert.Equal(t, "unknown", ChangeClass(-1).String())
+}
+
+func TestDiffAPI(t *testing.T) {
+	oldFS := fstest.MapFS{
+		"go.mod": {Data: []byte("module github.com/octocat/test\n")},
+		"lookup/lookup.go": {Data: []byte(`package lookup
+
+type ID string
+
+type Request struct {
+	ID ID
+	trace string
+}
+
+type Response struct {
+	Name string
+}
+
+type Options struct {
+	Timeout int
+}
+
+type Service interface {
+	Lookup(*Request) (*Response, error)
+}
+
+type Handler func(req *Request) error
+
+type service struct{}
+
+func New() Service { return &service{} }
+
+func Get(id ID) error { return nil }
+
+func (s *service) Lookup(*Request) (*Response, error) { return nil, nil }
+
+func (r *Request) Validate() error { return nil }
+
+func Must(s Service, err error) Service { return s }
+
+func helper() {}
+`)},
+	}
+
+	newFS := fstest.MapFS{
+		"go.mod": {Data: []byte("module github.com/octocat/test\n")},
+		"lookup/lookup.go": {Data: []byte(`package lookup
+
+type ID int
+
+type Request struct {
+	ID ID
+	span string
+}
+
+type Response struct {
+	Name  string
+	Score float64
+}
+
+type Options struct {
+	Timeout int
+}
+
+type Service interface {
+	Lookup(req *Request) (resp *Response, err error)
+}
+
+type Handler func(r *Request) (err error)
+
+type Status int
+
+type service struct{}
+
+func New(opts Options) Service { return &service{} }
+
+func Get(key ID) error { return nil }
+
+func (s *service) Lookup(*Request) (*Response, error) { return nil, nil }
+
+func (s *service) Close() error { return nil }
+
+func (r *Request) String() string { return "" }
+
+func helper(int) {}
+`)},
+	}
+
+	parse := func(fsys fstest.MapFS) *Result {
+		c, r := NewResultConsumer()
+		p := &parser{
+			ui:        ui.NewNop(),
+			consumers: []*Consumer{c},
+		}
+
+		err := p.ParseFS(fsys, "./...", ParseOptions{})
+		assert.NoError(t, err)
+
+		return r
+	}
+
+	// Renaming parameters and results is not a change
+	changes := DiffAPI(parse(oldFS), parse(newFS))
+
+	assert.Equal(t, []APIChange{
+		{ImportPath: "github.com/octocat/test/lookup", Name: "ID", Change: "changed", Class: Breaking},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Must", Change: "removed", Class: Breaking},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "New", Change: "changed", Class: Breaking},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Request", Change: "changed", Class: NonBreaking},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Request.String", Change: "added", Class: Additive},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Request.Validate", Change: "removed", Class: Breaking},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Response", Change: "changed", Class: Additive},
+		{ImportPath: "github.com/octocat/test/lookup", Name: "Status", Change: "added", Class: Additive},
+	}, changes)
+}
@@ -0,0 +1,779 @@
+package parser
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	goast "go/ast"
+	goprinter "go/printer"
+	gotoken "go/token"
+	gotypes "go/types"
+)
+
+// printerConfig is the go/printer configuration used by gofmt.
+var printerConfig = &goprinter.Config{
+	Mode:     goprinter.UseSpaces | goprinter.TabIndent,
+	Tabwidth: 8,
+}
+
+// EmbeddedTypes returns the types embedded in a struct type (fields with no names).
+func EmbeddedTypes(st *goast.StructType) []goast.Expr {
+	embedded := []goast.Expr{}
+	if st.Fields == nil {
+		return embedded
+	}
+
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			embedded = append(embedded, field.Type)
+		}
+	}
+
+	return embedded
+}
+
+// Fields returns the fields of a struct type with one entry per field name.
+// Fields declared together (e.g. X, Y int) share the same type, tag, and comments.
+// Comments are only available if the file was parsed with comments.
+func Fields(st *goast.StructType) []Field {
+	fields := []Field{}
+	if st.Fields == nil {
+		return fields
+	}
+
+	for _, field := range st.Fields.List {
+		f := Field{
+			Type:    field.Type,
+			Doc:     strings.TrimSpace(field.Doc.Text()),
+			Comment: strings.TrimSpace(field.Comment.Text()),
+		}
+
+		if field.Tag != nil {
+			if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+				f.Tag = reflect.StructTag(tag)
+			}
+		}
+
+		if len(field.Names) == 0 {
+			f.Name = InferName(field.Type)
+			f.Embedded = true
+			fields = append(fields, f)
+			continue
+		}
+
+		for _, name := range field.Names {
+			f.Name = name.Name
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// IsComparable determines whether or not values of a struct type are comparable (e.g. usable as map keys).
+// A struct is not comparable if any of its fields is a slice, map, or func type, including fields of nested structs and arrays.
+// Named types in the same package are followed using the resolver, and the resolver can be nil.
+// Named types that cannot be resolved (e.g. types from other packages or type parameters) are assumed to be comparable.
+func IsComparable(st *goast.StructType, resolve func(name string) *Type) bool {
+	return isComparable(st, resolve, map[string]bool{})
+}
+
+func isComparable(expr goast.Expr, resolve func(string) *Type, visited map[string]bool) bool {
+	switch v := expr.(type) {
+	case *goast.ParenExpr:
+		return isComparable(v.X, resolve, visited)
+
+	case *goast.StructType:
+		if v.Fields == nil {
+			return true
+		}
+
+		for _, field := range v.Fields.List {
+			if !isComparable(field.Type, resolve, visited) {
+				return false
+			}
+		}
+
+	case *goast.ArrayType:
+		// Slices
+		if v.Len == nil {
+			return false
+		}
+		return isComparable(v.Elt, resolve, visited)
+
+	case *goast.MapType, *goast.FuncType:
+		return false
+
+	case *goast.IndexExpr:
+		return isComparable(v.X, resolve, visited)
+
+	case *goast.IndexListExpr:
+		return isComparable(v.X, resolve, visited)
+
+	case *goast.Ident:
+		if resolve == nil || visited[v.Name] {
+			return true
+		}
+
+		visited[v.Name] = true
+		if t := resolve(v.Name); t != nil && t.Expr != nil {
+			return isComparable(t.Expr, resolve, visited)
+		}
+	}
+
+	// Pointers, channels, interfaces, and builtin types
+	return true
+}
+
+// EmbeddedInterfaces returns the interfaces embedded in an interface type.
+// Methods and type constraints (e.g. ~int | ~string) are not included.
+func EmbeddedInterfaces(it *goast.InterfaceType) []goast.Expr {
+	embedded := []goast.Expr{}
+	if it.Methods == nil {
+		return embedded
+	}
+
+	for _, field := range it.Methods.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+
+		switch field.Type.(type) {
+		case *goast.Ident, *goast.SelectorExpr, *goast.IndexExpr, *goast.IndexListExpr:
+			embedded = append(embedded, field.Type)
+		}
+	}
+
+	return embedded
+}
+
+// IsVariadic determines whether or not a function type is variadic.
+func IsVariadic(ft *goast.FuncType) bool {
+	_, ok := VariadicElem(ft)
+	return ok
+}
+
+// VariadicElem returns the element type of the variadic parameter of a function type (e.g. string for ...string).
+func VariadicElem(ft *goast.FuncType) (goast.Expr, bool) {
+	if ft.Params == nil || len(ft.Params.List) == 0 {
+		return nil, false
+	}
+
+	last := ft.Params.List[len(ft.Params.List)-1]
+	if ellipsis, ok := last.Type.(*goast.Ellipsis); ok {
+		return ellipsis.Elt, true
+	}
+
+	return nil, false
+}
+
+// RenameIdent renames all identifiers with a given name in a file and returns the number of renamed identifiers.
+// Scopes are not resolved, so local declarations shadowing the name (and their references) are renamed too.
+// Fields and methods are never renamed, since their uses through selectors (e.g. x.Name) cannot be resolved without type information.
+// So, selectors, field and method declarations, and keys of struct literals (e.g. Request{Name: ""}) are left untouched.
+// Literal types are resolved using the type declarations in the file; literals of types that cannot be resolved (e.g. lookup.Request) are assumed to be struct literals.
+func RenameIdent(file *goast.File, oldName, newName string) int {
+	// Collect the type declarations for resolving literal types
+	specs := make(map[string]*goast.TypeSpec)
+	goast.Inspect(file, func(n goast.Node) bool {
+		if spec, ok := n.(*goast.TypeSpec); ok {
+			specs[spec.Name.Name] = spec
+		}
+		return true
+	})
+
+	// Collect the identifiers of fields and methods to skip
+	skip := make(map[*goast.Ident]bool)
+	// The types of literals with elided types (e.g. the elements of []Request{{Name: ""}})
+	elided := make(map[*goast.CompositeLit]goast.Expr)
+	goast.Inspect(file, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.SelectorExpr:
+			skip[v.Sel] = true
+
+		case *goast.StructType:
+			skipFieldNames(v.Fields, skip)
+
+		case *goast.InterfaceType:
+			skipFieldNames(v.Methods, skip)
+
+		case *goast.FuncDecl:
+			if v.Recv != nil {
+				skip[v.Name] = true
+			}
+
+		case *goast.CompositeLit:
+			typ := v.Type
+			if typ == nil {
+				typ = elided[v]
+			}
+
+			// Keys of map, slice, and array literals are expressions
+			switch t := underlyingType(typ, specs).(type) {
+			case *goast.MapType:
+				for _, elt := range v.Elts {
+					if kv, ok := elt.(*goast.KeyValueExpr); ok {
+						elideType(kv.Key, t.Key, elided)
+						elideType(kv.Value, t.Value, elided)
+					}
+				}
+
+			case *goast.ArrayType:
+				for _, elt := range v.Elts {
+					if kv, ok := elt.(*goast.KeyValueExpr); ok {
+						elt = kv.Value
+					}
+					elideType(elt, t.Elt, elided)
+				}
+
+			case *goast.StructType, nil:
+				for _, elt := range v.Elts {
+					if kv, ok := elt.(*goast.KeyValueExpr); ok {
+						if id, ok := kv.Key.(*goast.Ident); ok {
+							skip[id] = true
+						}
+					}
+				}
+			}
+		}
+
+		return true
+	})
+
+	count := 0
+	goast.Inspect(file, func(n goast.Node) bool {
+		if id, ok := n.(*goast.Ident); ok && id.Name == oldName && !skip[id] {
+			id.Name = newName
+			count++
+		}
+		return true
+	})
+
+	return count
+}
+
+// underlyingType resolves a type expression to a struct, map, or array type using the type declarations in a file.
+// It returns nil if the type cannot be resolved (e.g. imported types or type parameters).
+func underlyingType(expr goast.Expr, specs map[string]*goast.TypeSpec) goast.Expr {
+	// Bound the number of steps to guard against invalid recursive declarations (e.g. type A B; type B A)
+	for i := 0; i <= len(specs); i++ {
+		switch v := goast.Unparen(expr).(type) {
+		case *goast.StructType, *goast.MapType, *goast.ArrayType:
+			return v
+		case *goast.IndexExpr:
+			expr = v.X
+		case *goast.IndexListExpr:
+			expr = v.X
+		case *goast.Ident:
+			spec, ok := specs[v.Name]
+			if !ok {
+				return nil
+			}
+			expr = spec.Type
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// elideType records the type of a composite literal with an elided type (e.g. {Name: ""} in []*Request{{Name: ""}}).
+func elideType(expr, typ goast.Expr, elided map[*goast.CompositeLit]goast.Expr) {
+	// The elided type of a pointer element is the pointer base type
+	if star, ok := typ.(*goast.StarExpr); ok {
+		typ = star.X
+	}
+
+	if lit, ok := expr.(*goast.CompositeLit); ok && lit.Type == nil {
+		elided[lit] = typ
+	}
+}
+
+func skipFieldNames(fields *goast.FieldList, skip map[*goast.Ident]bool) {
+	if fields == nil {
+		return
+	}
+
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			skip[name] = true
+		}
+	}
+}
+
+// Synopsis returns the first sentence of a comment, similar to go/doc.Synopsis.
+// The sentence ends at the first period followed by a space that does not follow a single uppercase letter (e.g. "J. Doe"),
+// or at the end of the first paragraph. Line breaks are replaced with single spaces.
+func Synopsis(comment *goast.CommentGroup) string {
+	if comment == nil {
+		return ""
+	}
+
+	text := comment.Text()
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	var ppp, pp, p rune
+	for i, r := range text {
+		if r == ' ' && p == '.' && (!unicode.IsUpper(pp) || unicode.IsUpper(ppp)) {
+			return text[:i]
+		}
+		ppp, pp, p = pp, p, r
+	}
+
+	return text
+}
+
+var majorVersionRegexp = regexp.MustCompile(`^v[0-9]+$`)
+
+// packageName guesses the name of a package from its import path using the same rules as goimports
+// (e.g. github.com/octocat/test/v2 --> test, gopkg.in/yaml.v3 --> yaml, and github.com/mattn/go-sqlite3 --> sqlite3).
+// The major version element is skipped, a go- prefix is removed, and the name is cut at the first non-identifier character.
+// The guessed name is not necessarily a valid identifier (e.g. it can be empty or start with a digit).
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionRegexp.MatchString(name) {
+		if dir := path.Dir(importPath); dir != "." {
+			name = path.Base(dir)
+		}
+	}
+
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// RewriteImports rewrites the import paths in a file using a mapping of old to new import paths
+// and returns the number of changes (rewritten imports and qualified identifiers).
+// If an import has no explicit name and the package name changes with the new import path,
+// the qualified identifiers using the old package name (e.g. old.Request) are updated too.
+// Package names are guessed from the import paths using the same rules as goimports (e.g. gopkg.in/yaml.v3 --> yaml).
+// If the guessed package name for the new import path is not a valid identifier, the import is aliased to the old package name instead.
+// Scopes are not resolved, so selectors on local declarations shadowing the old package name are updated too.
+func RewriteImports(file *goast.File, mapping map[string]string) int {
+	count := 0
+
+	// Package names that need to be updated in qualified identifiers
+	renames := make(map[string]string)
+
+	for _, spec := range file.Imports {
+		oldPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		newPath, ok := mapping[oldPath]
+		if !ok || newPath == oldPath {
+			continue
+		}
+
+		spec.Path.Value = strconv.Quote(newPath)
+		count++
+
+		if spec.Name == nil {
+			if oldName, newName := packageName(oldPath), packageName(newPath); oldName != newName && gotoken.IsIdentifier(oldName) {
+				if gotoken.IsIdentifier(newName) {
+					renames[oldName] = newName
+				} else {
+					// The qualified identifiers keep using the old package name through an alias
+					spec.Name = goast.NewIdent(oldName)
+				}
+			}
+		}
+	}
+
+	if len(renames) == 0 {
+		return count
+	}
+
+	goast.Inspect(file, func(n goast.Node) bool {
+		if sel, ok := n.(*goast.SelectorExpr); ok {
+			if x, ok := sel.X.(*goast.Ident); ok {
+				if newName, ok := renames[x.Name]; ok {
+					x.Name = newName
+					count++
+				}
+			}
+		}
+		return true
+	})
+
+	return count
+}
+
+// UnusedImports returns the imports of a file whose package names are not used in any qualified identifier (e.g. fmt.Println).
+// Blank imports, dot imports, and the cgo pseudo-package "C" are never considered unused.
+// Package names are guessed from the import paths unless imports are named.
+// Scopes are not resolved, so a package name shadowed by a local declaration is considered used.
+func UnusedImports(file *goast.File) []*goast.ImportSpec {
+	used := make(map[string]bool)
+	goast.Inspect(file, func(n goast.Node) bool {
+		if sel, ok := n.(*goast.SelectorExpr); ok {
+			if id, ok := sel.X.(*goast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	unused := []*goast.ImportSpec{}
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || importPath == "C" {
+			continue
+		}
+
+		name := packageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		if name != "_" && name != "." && !used[name] {
+			unused = append(unused, spec)
+		}
+	}
+
+	return unused
+}
+
+// StmtCount returns the number of statements in a block statement, including the nested ones.
+// Nested statements are counted in if, for, switch, and select bodies as well as function literals.
+// Block statements themselves are not counted, but case and comm clauses are.
+func StmtCount(body *goast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+
+	count := 0
+	goast.Inspect(body, func(n goast.Node) bool {
+		switch n.(type) {
+		case *goast.BlockStmt:
+		case goast.Stmt:
+			count++
+		}
+		return true
+	})
+
+	return count
+}
+
+// CyclomaticComplexity returns the cyclomatic complexity of a function body.
+// The complexity is one plus the number of decision points: if, for, and range statements,
+// non-default case and comm clauses, and && and || operators.
+// Decision points in function literals are also counted.
+func CyclomaticComplexity(body *goast.BlockStmt) int {
+	complexity := 1
+	if body == nil {
+		return complexity
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.IfStmt, *goast.ForStmt, *goast.RangeStmt:
+			complexity++
+		case *goast.CaseClause:
+			if v.List != nil {
+				complexity++
+			}
+		case *goast.CommClause:
+			if v.Comm != nil {
+				complexity++
+			}
+		case *goast.BinaryExpr:
+			if v.Op == gotoken.LAND || v.Op == gotoken.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+
+	return complexity
+}
+
+// TopLevelStmts returns the top-level statements of a function body in source order.
+func TopLevelStmts(body *goast.BlockStmt) []goast.Stmt {
+	stmts := []goast.Stmt{}
+	if body == nil {
+		return stmts
+	}
+
+	return append(stmts, body.List...)
+}
+
+// Assignments returns the assignment statements (including short variable declarations) of a function body in source order.
+// Assignments in nested blocks and function literals are included.
+func Assignments(body *goast.BlockStmt) []*goast.AssignStmt {
+	stmts := []*goast.AssignStmt{}
+	if body == nil {
+		return stmts
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		if v, ok := n.(*goast.AssignStmt); ok {
+			stmts = append(stmts, v)
+		}
+		return true
+	})
+
+	return stmts
+}
+
+// DeferredCalls returns the calls deferred by a function body in source order.
+// Defer statements in nested blocks are included, but defer statements in function literals are not,
+// since they are deferred by the function literals rather than the function itself.
+func DeferredCalls(body *goast.BlockStmt) []*goast.CallExpr {
+	calls := []*goast.CallExpr{}
+	if body == nil {
+		return calls
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.FuncLit:
+			return false
+		case *goast.DeferStmt:
+			calls = append(calls, v.Call)
+		}
+		return true
+	})
+
+	return calls
+}
+
+// ReturnStatements returns the return statements of a function body in source order.
+// Return statements in nested blocks are included, but return statements in function literals are not,
+// since they return from the function literals rather than the function itself.
+func ReturnStatements(body *goast.BlockStmt) []*goast.ReturnStmt {
+	stmts := []*goast.ReturnStmt{}
+	if body == nil {
+		return stmts
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.FuncLit:
+			return false
+		case *goast.ReturnStmt:
+			stmts = append(stmts, v)
+		}
+		return true
+	})
+
+	return stmts
+}
+
+// ReceiverFieldAccess returns the sorted names of the receiver fields read and written in a method body.
+// A field is written if it is assigned, incremented, or decremented, directly or through an element or a nested field
+// (e.g. r.count++, r.items[i] = item, or r.config.Timeout = timeout).
+// Compound assignments, increments, and decrements (e.g. r.total += n or r.count++) both read and write a field.
+// Selectors called directly (e.g. r.Lookup()) are assumed to be methods and ignored.
+// Scopes are not resolved, so accessing a local declaration shadowing the receiver name counts as a field access too.
+func ReceiverFieldAccess(recvName string, body *goast.BlockStmt) (reads, writes []string) {
+	reads, writes = []string{}, []string{}
+	if recvName == "" || recvName == "_" || body == nil {
+		return reads, writes
+	}
+
+	// fieldSelector returns the receiver field selector an expression is rooted at, if any.
+	fieldSelector := func(expr goast.Expr) *goast.SelectorExpr {
+		for {
+			switch v := expr.(type) {
+			case *goast.ParenExpr:
+				expr = v.X
+			case *goast.StarExpr:
+				expr = v.X
+			case *goast.IndexExpr:
+				expr = v.X
+			case *goast.IndexListExpr:
+				expr = v.X
+			case *goast.SelectorExpr:
+				if id, ok := v.X.(*goast.Ident); ok && id.Name == recvName {
+					return v
+				}
+				expr = v.X
+			default:
+				return nil
+			}
+		}
+	}
+
+	readSet := make(map[string]bool)
+	writeSet := make(map[string]bool)
+	// written maps the selectors written to whether they are also read
+	written := make(map[*goast.SelectorExpr]bool)
+	called := make(map[*goast.SelectorExpr]bool)
+
+	markWritten := func(read bool, exprs ...goast.Expr) {
+		for _, expr := range exprs {
+			if sel := fieldSelector(expr); sel != nil {
+				written[sel] = read
+			}
+		}
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.AssignStmt:
+			if v.Tok != gotoken.DEFINE {
+				// Compound assignments (e.g. +=) read the operands too
+				markWritten(v.Tok != gotoken.ASSIGN, v.Lhs...)
+			}
+		case *goast.IncDecStmt:
+			markWritten(true, v.X)
+		case *goast.RangeStmt:
+			if v.Tok == gotoken.ASSIGN {
+				markWritten(false, v.Key, v.Value)
+			}
+		case *goast.CallExpr:
+			if sel, ok := v.Fun.(*goast.SelectorExpr); ok {
+				called[sel] = true
+			}
+		case *goast.SelectorExpr:
+			if id, ok := v.X.(*goast.Ident); ok && id.Name == recvName {
+				if read, ok := written[v]; ok {
+					writeSet[v.Sel.Name] = true
+					if read {
+						readSet[v.Sel.Name] = true
+					}
+				} else if !called[v] {
+					readSet[v.Sel.Name] = true
+				}
+			}
+		}
+		return true
+	})
+
+	return sortedKeys(readSet), sortedKeys(writeSet)
+}
+
+// ExprString returns the Go source representation of an expression.
+// The file set is used for preserving line breaks in multi-line expressions and can be nil.
+func ExprString(fset *gotoken.FileSet, expr goast.Expr) string {
+	if fset == nil {
+		fset = gotoken.NewFileSet()
+	}
+
+	buf := new(bytes.Buffer)
+	_ = printerConfig.Fprint(buf, fset, expr)
+	return buf.String()
+}
+
+// exprString returns the Go source representation of an expression without position information.
+func exprString(expr goast.Expr) string {
+	return ExprString(nil, expr)
+}
+
+// ZeroValue returns an expression for the zero value of a type expression.
+// Without type information, the underlying type of a named type (e.g. Request, lookup.Request, or Set[string]) is unknown,
+// so its zero value is the expression *new(T), which is valid for any type.
+// File.ZeroValue uses type information when available for more idiomatic zero values (e.g. "" for type ID string).
+func ZeroValue(expr goast.Expr) goast.Expr {
+	expr = goast.Unparen(expr)
+
+	switch v := expr.(type) {
+	case *goast.Ident:
+		switch v.Name {
+		case "bool":
+			return goast.NewIdent("false")
+		case "string":
+			return &goast.BasicLit{Kind: gotoken.STRING, Value: `""`}
+		case "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+			"byte", "rune", "float32", "float64", "complex64", "complex128":
+			return &goast.BasicLit{Kind: gotoken.INT, Value: "0"}
+		case "error", "any":
+			return goast.NewIdent("nil")
+		}
+
+	case *goast.ArrayType:
+		// Slices
+		if v.Len == nil {
+			return goast.NewIdent("nil")
+		}
+		return &goast.CompositeLit{Type: v}
+
+	case *goast.StructType:
+		return &goast.CompositeLit{Type: v}
+
+	case *goast.StarExpr, *goast.MapType, *goast.ChanType, *goast.FuncType, *goast.InterfaceType:
+		return goast.NewIdent("nil")
+	}
+
+	// Named types with unknown underlying types
+	return newZeroValue(expr)
+}
+
+// zeroValueOf returns an expression for the zero value of a type expression given its type.
+func zeroValueOf(expr goast.Expr, typ gotypes.Type) goast.Expr {
+	expr = goast.Unparen(expr)
+
+	// The underlying type of a type parameter is its constraint interface
+	if _, ok := gotypes.Unalias(typ).(*gotypes.TypeParam); ok {
+		return newZeroValue(expr)
+	}
+
+	switch u := typ.Underlying().(type) {
+	case *gotypes.Basic:
+		switch {
+		case u.Info()&gotypes.IsBoolean != 0:
+			return goast.NewIdent("false")
+		case u.Info()&gotypes.IsString != 0:
+			return &goast.BasicLit{Kind: gotoken.STRING, Value: `""`}
+		case u.Info()&gotypes.IsNumeric != 0:
+			return &goast.BasicLit{Kind: gotoken.INT, Value: "0"}
+		case u.Kind() == gotypes.UnsafePointer:
+			return goast.NewIdent("nil")
+		}
+
+	case *gotypes.Pointer, *gotypes.Slice, *gotypes.Map, *gotypes.Chan, *gotypes.Signature, *gotypes.Interface:
+		return goast.NewIdent("nil")
+
+	case *gotypes.Struct, *gotypes.Array:
+		return &goast.CompositeLit{Type: expr}
+	}
+
+	return newZeroValue(expr)
+}
+
+// newZeroValue returns the expression *new(T) for the zero value of any type T.
+func newZeroValue(expr goast.Expr) goast.Expr {
+	return &goast.StarExpr{
+		X: &goast.CallExpr{
+			Fun:  goast.NewIdent("new"),
+			Args: []goast.Expr{expr},
+		},
+	}
+}
+
+// TypeParamsOf returns the type parameters of a generic function type with one entry per name.
+// Type parameters sharing a constraint (e.g. [K, V any]) have the same constraint expression.
+func TypeParamsOf(ft *goast.FuncType) []TypeParam {
+	params := []TypeParam{}
+	if ft.TypeParams == nil {
+		return params
+	}
+
+	for _, field := range ft.TypeParams.List {
+		for _, name := range field.Names {
+			params = append(params, TypeParam{
+				Name:       name.Name,
+				Constraint: field.Type,
+			})
+		}
+	}
+
+	return params
+}
@@ -0,0 +1,1197 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	goast "go/ast"
+	goparser "go/parser"
+	goprinter "go/printer"
+	gotoken "go/token"
+	gotypes "go/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedNames []string
+	}{
+		{
+			name:          "NoEmbedded",
+			src:           `struct { ID string }`,
+			expectedNames: []string{},
+		},
+		{
+			name: "Embedded",
+			src: `struct {
+				sync.Mutex
+				*Base
+				ID string
+			}`,
+			expectedNames: []string{"sync.Mutex", "*Base"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.src)
+			assert.NoError(t, err)
+
+			names := []string{}
+			for _, e := range EmbeddedTypes(expr.(*goast.StructType)) {
+				names = append(names, exprString(e))
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	src := `package lookup
+
+type Request struct {
+	// Embedded context.
+	*lookup.Base
+
+	// ID is the request identifier.
+	// It is required.
+	ID string ` + "`json:\"id\"`" + ` // unique
+
+	X, Y int // coordinates
+}
+`
+
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "lookup.go", src, goparser.ParseComments)
+	assert.NoError(t, err)
+
+	st := file.Decls[0].(*goast.GenDecl).Specs[0].(*goast.TypeSpec).Type.(*goast.StructType)
+	fields := Fields(st)
+
+	assert.Len(t, fields, 4)
+
+	assert.Equal(t, "Base", fields[0].Name)
+	assert.Equal(t, "*lookup.Base", exprString(fields[0].Type))
+	assert.True(t, fields[0].Embedded)
+	assert.Equal(t, "Embedded context.", fields[0].Doc)
+	assert.Empty(t, fields[0].Comment)
+
+	assert.Equal(t, "ID", fields[1].Name)
+	assert.False(t, fields[1].Embedded)
+	assert.Equal(t, "id", fields[1].Tag.Get("json"))
+	assert.Equal(t, "ID is the request identifier.\nIt is required.", fields[1].Doc)
+	assert.Equal(t, "unique", fields[1].Comment)
+
+	assert.Equal(t, "X", fields[2].Name)
+	assert.Equal(t, "Y", fields[3].Name)
+	assert.Empty(t, fields[3].Doc)
+	assert.Equal(t, "coordinates", fields[2].Comment)
+	assert.Equal(t, "coordinates", fields[3].Comment)
+
+	assert.Empty(t, Fields(&goast.StructType{}))
+}
+
+func TestIsComparable(t *testing.T) {
+	src := `package lookup
+
+type ID string
+
+type Tags []string
+
+type Key struct {
+	ID    ID
+	Parts [2]string
+}
+
+type Node struct {
+	Next *Node
+	Done chan struct{}
+	Err  error
+}
+
+type Box[T any] struct {
+	Value T
+}
+
+type Filter = func(string) bool
+
+type Request struct {
+	Key  Key
+	Tags Tags
+}
+
+type Options struct {
+	Filter Filter
+}
+
+type Tree struct {
+	Root  Node
+	Boxes [4]Box[int]
+}
+
+type Batch struct {
+	Items [2]struct {
+		IDs []ID
+	}
+}
+
+type Cache struct {
+	Entries map[string]Key
+}
+
+type Remote struct {
+	Time time.Time
+}
+`
+
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "lookup.go", src, 0)
+	assert.NoError(t, err)
+
+	types := map[string]*Type{}
+	for _, decl := range file.Decls {
+		for _, spec := range decl.(*goast.GenDecl).Specs {
+			ts := spec.(*goast.TypeSpec)
+			types[ts.Name.Name] = &Type{Name: ts.Name.Name, Expr: ts.Type}
+		}
+	}
+
+	resolve := func(name string) *Type {
+		return types[name]
+	}
+
+	tests := []struct {
+		name               string
+		typeName           string
+		resolve            func(string) *Type
+		expectedComparable bool
+	}{
+		{"Basic", "Key", resolve, true},
+		{"Pointers", "Node", resolve, true},
+		{"NamedSlice", "Request", resolve, false},
+		{"NamedSlice_NoResolver", "Request", nil, true},
+		{"AliasFunc", "Options", resolve, false},
+		{"NestedStructs", "Tree", resolve, true},
+		{"ArrayOfStructs", "Batch", resolve, false},
+		{"Map", "Cache", resolve, false},
+		{"OtherPackage", "Remote", resolve, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := types[tc.typeName].Expr.(*goast.StructType)
+
+			assert.Equal(t, tc.expectedComparable, IsComparable(st, tc.resolve))
+		})
+	}
+}
+
+func TestEmbeddedInterfaces(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedNames []string
+	}{
+		{
+			name:          "NoEmbedded",
+			src:           `interface { Close() error }`,
+			expectedNames: []string{},
+		},
+		{
+			name: "Embedded",
+			src: `interface {
+				io.Reader
+				Lookuper[Request]
+				~int | ~string
+				Close() error
+			}`,
+			expectedNames: []string{"io.Reader", "Lookuper[Request]"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.src)
+			assert.NoError(t, err)
+
+			names := []string{}
+			for _, e := range EmbeddedInterfaces(expr.(*goast.InterfaceType)) {
+				names = append(names, exprString(e))
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	tests := []struct {
+		name               string
+		src                string
+		expectedIsVariadic bool
+		expectedElem       string
+	}{
+		{
+			name:               "NoParams",
+			src:                `func()`,
+			expectedIsVariadic: false,
+		},
+		{
+			name:               "NotVariadic",
+			src:                `func(format string, args []any)`,
+			expectedIsVariadic: false,
+		},
+		{
+			name:               "Variadic",
+			src:                `func(format string, args ...any)`,
+			expectedIsVariadic: true,
+			expectedElem:       "any",
+		},
+		{
+			name:               "VariadicPointer",
+			src:                `func(opts ...*Option)`,
+			expectedIsVariadic: true,
+			expectedElem:       "*Option",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.src)
+			assert.NoError(t, err)
+			ft := expr.(*goast.FuncType)
+
+			assert.Equal(t, tc.expectedIsVariadic, IsVariadic(ft))
+
+			elem, ok := VariadicElem(ft)
+			assert.Equal(t, tc.expectedIsVariadic, ok)
+			if ok {
+				assert.Equal(t, tc.expectedElem, exprString(elem))
+			} else {
+				assert.Nil(t, elem)
+			}
+		})
+	}
+}
+
+const renameSrc = `package lookup
+
+type Request struct {
+	ID string
+}
+
+func Lookup(req *Request) *Request {
+	r := &Request{ID: req.ID}
+	return r
+}
+
+func ID(req Request) string {
+	return req.ID
+}
+
+type Service interface {
+	Lookup(*Request) *Request
+}
+
+type service struct{}
+
+func (s *service) Lookup(req *Request) *Request {
+	return Lookup(req)
+}
+
+func New() Service {
+	s := &service{}
+	_ = s.Lookup(&Request{})
+	return s
+}
+
+const Default = "default"
+
+type Index map[string]*Request
+
+type Requests []Request
+
+var index = Index{Default: {ID: Default}}
+
+var requests = Requests{{ID: Default}}
+`
+
+func TestRenameIdent(t *testing.T) {
+	tests := []struct {
+		name          string
+		oldName       string
+		newName       string
+		expectedCount int
+	}{
+		{
+			name:          "NotFound",
+			oldName:       "Response",
+			newName:       "Reply",
+			expectedCount: 0,
+		},
+		{
+			name:          "Type",
+			oldName:       "Request",
+			newName:       "Query",
+			expectedCount: 12,
+		},
+		{
+			name:          "SkipFields",
+			oldName:       "ID",
+			newName:       "Key",
+			expectedCount: 1,
+		},
+		{
+			name:          "SkipMethods",
+			oldName:       "Lookup",
+			newName:       "Find",
+			expectedCount: 2,
+		},
+		{
+			name:          "NamedMapKeys",
+			oldName:       "Default",
+			newName:       "Fallback",
+			expectedCount: 4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fset := gotoken.NewFileSet()
+			file, err := goparser.ParseFile(fset, "lookup.go", renameSrc, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			count := RenameIdent(file, tc.oldName, tc.newName)
+
+			assert.Equal(t, tc.expectedCount, count)
+
+			// The renamed file should still type check
+			var buf bytes.Buffer
+			assert.NoError(t, goprinter.Fprint(&buf, fset, file))
+
+			file, err = goparser.ParseFile(fset, "lookup.go", buf.Bytes(), 0)
+			assert.NoError(t, err)
+
+			_, err = new(gotypes.Config).Check("lookup", fset, []*goast.File{file}, nil)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSynopsis(t *testing.T) {
+	tests := []struct {
+		name             string
+		comment          *goast.CommentGroup
+		expectedSynopsis string
+	}{
+		{
+			name:             "Nil",
+			comment:          nil,
+			expectedSynopsis: "",
+		},
+		{
+			name: "SingleSentence",
+			comment: &goast.CommentGroup{
+				List: []*goast.Comment{
+					{Text: "// Package lookup provides a lookup service"},
+				},
+			},
+			expectedSynopsis: "Package lookup provides a lookup service",
+		},
+		{
+			name: "MultipleSentences",
+			comment: &goast.CommentGroup{
+				List: []*goast.Comment{
+					{Text: "// Package lookup provides"},
+					{Text: "// a lookup service. It is written by J. Doe."},
+				},
+			},
+			expectedSynopsis: "Package lookup provides a lookup service.",
+		},
+		{
+			name: "Initials",
+			comment: &goast.CommentGroup{
+				List: []*goast.Comment{
+					{Text: "// Package lookup is written by J. Doe. It provides a lookup service."},
+				},
+			},
+			expectedSynopsis: "Package lookup is written by J. Doe.",
+		},
+		{
+			name: "MultipleParagraphs",
+			comment: &goast.CommentGroup{
+				List: []*goast.Comment{
+					{Text: "// Package lookup provides a lookup service"},
+					{Text: "//"},
+					{Text: "// It is used for testing."},
+				},
+			},
+			expectedSynopsis: "Package lookup provides a lookup service",
+		},
+		{
+			name: "BlockComment",
+			comment: &goast.CommentGroup{
+				List: []*goast.Comment{
+					{Text: "/*\n  Package lookup provides a lookup service.\n  It is used for testing.\n*/"},
+				},
+			},
+			expectedSynopsis: "Package lookup provides a lookup service.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedSynopsis, Synopsis(tc.comment))
+		})
+	}
+}
+
+func TestStmtCount(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedCount int
+	}{
+		{
+			name:          "NoBody",
+			src:           `package main; func f()`,
+			expectedCount: 0,
+		},
+		{
+			name:          "Empty",
+			src:           `package main; func f() {}`,
+			expectedCount: 0,
+		},
+		{
+			name: "Flat",
+			src: `package main
+				func f() int {
+					a := 1
+					a++
+					return a
+				}`,
+			expectedCount: 3,
+		},
+		{
+			name: "Nested",
+			src: `package main
+				func f(n int) int {
+					sum := 0
+					for i := 0; i < n; i++ {
+						if i%2 == 0 {
+							sum += i
+						} else {
+							sum -= i
+						}
+					}
+					switch {
+					case sum > 0:
+						return 1
+					default:
+						return 0
+					}
+				}`,
+			// sum :=, for, i :=, i++, if, sum +=, sum -=, switch, 2 cases, 2 returns
+			expectedCount: 12,
+		},
+		{
+			name: "FuncLit",
+			src: `package main
+				func f() {
+					g := func() {
+						println()
+					}
+					g()
+				}`,
+			expectedCount: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			assert.Equal(t, tc.expectedCount, StmtCount(fd.Body))
+		})
+	}
+}
+
+func TestCyclomaticComplexity(t *testing.T) {
+	tests := []struct {
+		name               string
+		src                string
+		expectedComplexity int
+	}{
+		{
+			name:               "NoBody",
+			src:                `package main; func f()`,
+			expectedComplexity: 1,
+		},
+		{
+			name: "Linear",
+			src: `package main
+				func f() int {
+					a := 1
+					return a
+				}`,
+			expectedComplexity: 1,
+		},
+		{
+			name: "Branches",
+			src: `package main
+				func f(n int, ok bool) int {
+					if n > 0 && ok || n < -10 {
+						return 1
+					} else if n == 0 {
+						return 0
+					}
+					for i := range n {
+						_ = i
+					}
+					return -1
+				}`,
+			expectedComplexity: 6,
+		},
+		{
+			name: "SwitchSelect",
+			src: `package main
+				func f(n int, ch chan int) {
+					switch n {
+					case 1, 2:
+					case 3:
+					default:
+					}
+					select {
+					case <-ch:
+					default:
+					}
+					g := func() {
+						for {
+						}
+					}
+					g()
+				}`,
+			expectedComplexity: 5,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			assert.Equal(t, tc.expectedComplexity, CyclomaticComplexity(fd.Body))
+		})
+	}
+}
+
+func TestTopLevelStmts(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedStmts []string
+	}{
+		{
+			name:          "NoBody",
+			src:           `package main; func f()`,
+			expectedStmts: []string{},
+		},
+		{
+			name: "Nested",
+			src: `package main
+				func f(n int) int {
+					sum := 0
+					for i := 0; i < n; i++ {
+						sum += i
+					}
+					return sum
+				}`,
+			expectedStmts: []string{"*ast.AssignStmt", "*ast.ForStmt", "*ast.ReturnStmt"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			stmts := []string{}
+			for _, stmt := range TopLevelStmts(fd.Body) {
+				stmts = append(stmts, fmt.Sprintf("%T", stmt))
+			}
+
+			assert.Equal(t, tc.expectedStmts, stmts)
+		})
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedLines []int
+	}{
+		{
+			name:          "NoBody",
+			src:           `package main; func f()`,
+			expectedLines: []int{},
+		},
+		{
+			name: "Nested",
+			src: `package main
+				func f(n int) int {
+					sum := 0
+					for i := 0; i < n; i++ {
+						sum += i
+					}
+					g := func() {
+						sum = 0
+					}
+					var x int
+					x++
+					g()
+					return sum + x
+				}`,
+			expectedLines: []int{3, 4, 5, 7, 8},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fset := gotoken.NewFileSet()
+			file, err := goparser.ParseFile(fset, "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			lines := []int{}
+			for _, stmt := range Assignments(fd.Body) {
+				lines = append(lines, fset.Position(stmt.Pos()).Line)
+			}
+
+			assert.Equal(t, tc.expectedLines, lines)
+		})
+	}
+}
+
+func TestDeferredCalls(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedCalls []string
+	}{
+		{
+			name:          "NoBody",
+			src:           `package main; func f()`,
+			expectedCalls: []string{},
+		},
+		{
+			name: "Nested",
+			src: `package main
+				func f(files []*os.File) {
+					defer mu.Unlock()
+					for _, f := range files {
+						if f != nil {
+							defer f.Close()
+						}
+					}
+					go func() {
+						defer wg.Done()
+					}()
+					defer func() {
+						recover()
+					}()
+				}`,
+			expectedCalls: []string{"mu.Unlock()", "f.Close()", "func() {\n\trecover()\n}()"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			calls := []string{}
+			for _, call := range DeferredCalls(fd.Body) {
+				calls = append(calls, exprString(call))
+			}
+
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		name            string
+		src             string
+		expectedResults []string
+	}{
+		{
+			name:            "NoBody",
+			src:             `package main; func f()`,
+			expectedResults: []string{},
+		},
+		{
+			name: "Nested",
+			src: `package main
+				func f(n int) (int, error) {
+					g := func() int {
+						return 0
+					}
+					if n < 0 {
+						return 0, errors.New("negative")
+					}
+					switch n {
+					case 0:
+						return g(), nil
+					}
+					return n, nil
+				}`,
+			expectedResults: []string{`0, errors.New("negative")`, "g(), nil", "n, nil"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			results := []string{}
+			for _, stmt := range ReturnStatements(fd.Body) {
+				exprs := []string{}
+				for _, expr := range stmt.Results {
+					exprs = append(exprs, exprString(expr))
+				}
+				results = append(results, strings.Join(exprs, ", "))
+			}
+
+			assert.Equal(t, tc.expectedResults, results)
+		})
+	}
+}
+
+func TestReceiverFieldAccess(t *testing.T) {
+	tests := []struct {
+		name           string
+		src            string
+		expectedReads  []string
+		expectedWrites []string
+	}{
+		{
+			name:           "NoBody",
+			src:            `package main; func (s *service) f()`,
+			expectedReads:  []string{},
+			expectedWrites: []string{},
+		},
+		{
+			name:           "BlankReceiver",
+			src:            `package main; func (_ *service) f() { _ = 1 }`,
+			expectedReads:  []string{},
+			expectedWrites: []string{},
+		},
+		{
+			name: "ReadsAndWrites",
+			src: `package main
+				func (s *service) f(req *Request) (*Response, error) {
+					s.mu.Lock()
+					defer s.mu.Unlock()
+					s.count++
+					s.cache[req.ID] = req
+					s.config.Timeout = time.Second
+					*s.last = *req
+					for s.cursor = range s.items {
+						go func() {
+							s.errors += 1
+						}()
+					}
+					name := s.name
+					return s.lookup(name)
+				}`,
+			expectedReads:  []string{"count", "errors", "items", "mu", "name"},
+			expectedWrites: []string{"cache", "config", "count", "cursor", "errors", "last"},
+		},
+		{
+			name: "GenericReceiver",
+			src: `package main
+				func (s *Set[K, V]) Put(k K, v V) {
+					s.items[k] = v
+					s.size += 1
+					s.version++
+					s.last = k
+				}`,
+			expectedReads:  []string{"size", "version"},
+			expectedWrites: []string{"items", "last", "size", "version"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+			recvName := fd.Recv.List[0].Names[0].Name
+
+			reads, writes := ReceiverFieldAccess(recvName, fd.Body)
+
+			assert.Equal(t, tc.expectedReads, reads)
+			assert.Equal(t, tc.expectedWrites, writes)
+		})
+	}
+}
+
+func TestExprString(t *testing.T) {
+	const src = `package main
+
+type T struct {
+	Items  []*Item
+	Lookup map[string]func(context.Context, string) (int, error)
+}
+`
+
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", src, goparser.SkipObjectResolution)
+	assert.NoError(t, err)
+
+	st := file.Decls[0].(*goast.GenDecl).Specs[0].(*goast.TypeSpec).Type
+
+	tests := []struct {
+		name           string
+		fset           *gotoken.FileSet
+		expr           goast.Expr
+		expectedString string
+	}{
+		{
+			name:           "Slice",
+			fset:           nil,
+			expr:           &goast.ArrayType{Elt: &goast.StarExpr{X: goast.NewIdent("Item")}},
+			expectedString: "[]*Item",
+		},
+		{
+			name:           "Map",
+			fset:           fset,
+			expr:           st.(*goast.StructType).Fields.List[1].Type,
+			expectedString: "map[string]func(context.Context, string) (int, error)",
+		},
+		{
+			name:           "Struct",
+			fset:           fset,
+			expr:           st,
+			expectedString: "struct {\n\tItems  []*Item\n\tLookup map[string]func(context.Context, string) (int, error)\n}",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedString, ExprString(tc.fset, tc.expr))
+		})
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := []struct {
+		importPath   string
+		expectedName string
+	}{
+		{"fmt", "fmt"},
+		{"net/http", "http"},
+		{"github.com/octocat/test/v2", "test"},
+		{"gopkg.in/yaml.v3", "yaml"},
+		{"github.com/mattn/go-sqlite3", "sqlite3"},
+		{"github.com/octocat/lookup-service", "lookup"},
+		{"github.com/octocat/search.go", "search"},
+		{"v2", "v2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.importPath, func(t *testing.T) {
+			assert.Equal(t, tc.expectedName, packageName(tc.importPath))
+		})
+	}
+}
+
+func TestUnusedImports(t *testing.T) {
+	tests := []struct {
+		name            string
+		src             string
+		expectedImports []string
+	}{
+		{
+			name:            "NoImports",
+			src:             `package main`,
+			expectedImports: []string{},
+		},
+		{
+			name: "AllUsed",
+			src: `package main
+				import (
+					"fmt"
+					"github.com/octocat/test/v2"
+					http "net/http"
+				)
+				var _ http.Handler = test.New()
+				func main() { fmt.Println() }`,
+			expectedImports: []string{},
+		},
+		{
+			name: "Unused",
+			src: `package main
+				import (
+					"C"
+					"fmt"
+					"strings"
+					_ "embed"
+					. "errors"
+					lookup "github.com/octocat/lookup-service"
+					"github.com/octocat/search-service"
+				)
+				func main() { fmt.Println(New(""), search.New()) }`,
+			expectedImports: []string{`"strings"`, `"github.com/octocat/lookup-service"`},
+		},
+		{
+			name: "AssumedNames",
+			src: `package main
+				import (
+					"gopkg.in/yaml.v3"
+					"github.com/mattn/go-sqlite3"
+					"github.com/octocat/go-lookup/v2"
+					"github.com/octocat/search.go"
+					"github.com/octocat/unused-service"
+				)
+				var _ = yaml.Marshal
+				var _ = sqlite3.Version
+				var _ = lookup.New
+				var _ = search.New`,
+			expectedImports: []string{`"github.com/octocat/unused-service"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			imports := []string{}
+			for _, spec := range UnusedImports(file) {
+				imports = append(imports, spec.Path.Value)
+			}
+
+			assert.Equal(t, tc.expectedImports, imports)
+		})
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		expr          string
+		expectedValue string
+	}{
+		{"Bool", "bool", "false"},
+		{"String", "string", `""`},
+		{"Int", "int", "0"},
+		{"Float", "float64", "0"},
+		{"Byte", "byte", "0"},
+		{"Error", "error", "nil"},
+		{"Any", "any", "nil"},
+		{"Pointer", "*Request", "nil"},
+		{"Slice", "[]string", "nil"},
+		{"Map", "map[string]int", "nil"},
+		{"Chan", "chan error", "nil"},
+		{"Func", "func() error", "nil"},
+		{"Interface", "interface{ Close() error }", "nil"},
+		{"Paren", "(*Request)", "nil"},
+		{"Array", "[2]int", "[2]int{}"},
+		{"Struct", "struct{ ID string }", "struct{ ID string }{}"},
+		{"ParenStruct", "(struct{})", "struct{}{}"},
+		{"Named", "Request", "*new(Request)"},
+		{"Qualified", "lookup.Request", "*new(lookup.Request)"},
+		{"Generic", "Set[string]", "*new(Set[string])"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.expr)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectedValue, exprString(ZeroValue(expr)))
+		})
+	}
+}
+
+func TestTypeParamsOf(t *testing.T) {
+	tests := []struct {
+		name               string
+		src                string
+		expectedTypeParams []string
+	}{
+		{
+			name:               "NotGeneric",
+			src:                `package main; func f(s string) {}`,
+			expectedTypeParams: []string{},
+		},
+		{
+			name:               "Single",
+			src:                `package main; func f[T any](v T) {}`,
+			expectedTypeParams: []string{"T any"},
+		},
+		{
+			name:               "SharedConstraint",
+			src:                `package main; func f[K, V comparable, S ~[]V](m map[K]V) S { return nil }`,
+			expectedTypeParams: []string{"K comparable", "V comparable", "S ~[]V"},
+		},
+		{
+			name:               "UnionConstraint",
+			src:                `package main; func f[N int | float64](a, b N) N { return a + b }`,
+			expectedTypeParams: []string{"N int | float64"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+
+			typeParams := []string{}
+			for _, tp := range TypeParamsOf(fd.Type) {
+				typeParams = append(typeParams, tp.Name+" "+exprString(tp.Constraint))
+			}
+
+			assert.Equal(t, tc.expectedTypeParams, typeParams)
+		})
+	}
+}
+
+func TestRewriteImports(t *testing.T) {
+	const src = `package main
+
+import (
+	"fmt"
+
+	old "github.com/octocat/lookup"
+	"github.com/octocat/search"
+	"github.com/octocat/test/v2"
+)
+
+func main() {
+	fmt.Println(old.New(), search.New(), test.New())
+	var _ search.Query
+}
+`
+
+	tests := []struct {
+		name          string
+		mapping       map[string]string
+		expectedCount int
+		expectedSrc   string
+	}{
+		{
+			name:          "NoMatch",
+			mapping:       map[string]string{"github.com/octocat/query": "github.com/octocat/finder"},
+			expectedCount: 0,
+			expectedSrc:   src,
+		},
+		{
+			name: "NamedImport",
+			mapping: map[string]string{
+				"github.com/octocat/lookup": "github.com/octocat/finder",
+			},
+			expectedCount: 1,
+			expectedSrc:   strings.Replace(src, `old "github.com/octocat/lookup"`, `old "github.com/octocat/finder"`, 1),
+		},
+		{
+			name: "SamePackageName",
+			mapping: map[string]string{
+				"github.com/octocat/test/v2": "github.com/octocat/test/v3",
+			},
+			expectedCount: 1,
+			expectedSrc:   strings.Replace(src, `"github.com/octocat/test/v2"`, `"github.com/octocat/test/v3"`, 1),
+		},
+		{
+			name: "PackageNameChanged",
+			mapping: map[string]string{
+				"github.com/octocat/search": "github.com/octocat/finder",
+			},
+			expectedCount: 3,
+			expectedSrc: `package main
+
+import (
+	"fmt"
+
+	old "github.com/octocat/lookup"
+	"github.com/octocat/finder"
+	"github.com/octocat/test/v2"
+)
+
+func main() {
+	fmt.Println(old.New(), finder.New(), test.New())
+	var _ finder.Query
+}
+`,
+		},
+		{
+			name: "VersionSuffix",
+			mapping: map[string]string{
+				"github.com/octocat/search": "gopkg.in/search.v3",
+			},
+			expectedCount: 1,
+			expectedSrc: `package main
+
+import (
+	"fmt"
+
+	old "github.com/octocat/lookup"
+	"gopkg.in/search.v3"
+	"github.com/octocat/test/v2"
+)
+
+func main() {
+	fmt.Println(old.New(), search.New(), test.New())
+	var _ search.Query
+}
+`,
+		},
+		{
+			name: "InvalidPackageName",
+			mapping: map[string]string{
+				"github.com/octocat/search": "github.com/octocat/3d",
+			},
+			expectedCount: 1,
+			expectedSrc:   strings.Replace(src, `"github.com/octocat/search"`, `search "github.com/octocat/3d"`, 1),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fset := gotoken.NewFileSet()
+			file, err := goparser.ParseFile(fset, "", src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			count := RewriteImports(file, tc.mapping)
+
+			buf := new(bytes.Buffer)
+			assert.NoError(t, goprinter.Fprint(buf, fset, file))
+
+			assert.Equal(t, tc.expectedCount, count)
+			assert.Equal(t, tc.expectedSrc, buf.String())
+		})
+	}
+}
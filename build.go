@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	goast "go/ast"
+	"go/build/constraint"
+)
+
+// knownOS and knownArch are the GOOS and GOARCH values recognized in file names (see go/build/syslist.go).
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true, "illumos": true, "ios": true,
+	"js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true, "loong64": true,
+	"mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true,
+	"ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true, "sparc": true, "sparc64": true,
+	"wasm": true,
+}
+
+// buildConstraint returns the build constraint of a file or nil if the file is not constrained.
+// It combines the tags implied by the file name (e.g. *_linux_amd64.go) and the //go:build line.
+// Like the go command, // +build lines are only used if there is no //go:build line.
+// Build constraint lines are only available if comments are parsed.
+func buildConstraint(filename string, file *goast.File) constraint.Expr {
+	var expr constraint.Expr
+
+	and := func(x constraint.Expr) {
+		if expr == nil {
+			expr = x
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: x}
+		}
+	}
+
+	// File name suffixes (*_GOOS, *_GOARCH, *_GOOS_GOARCH)
+	name := strings.TrimSuffix(filepath.Base(filename), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	if parts := strings.Split(name, "_"); len(parts) > 1 {
+		n := len(parts)
+		switch {
+		case n > 2 && knownOS[parts[n-2]] && knownArch[parts[n-1]]:
+			and(&constraint.TagExpr{Tag: parts[n-2]})
+			and(&constraint.TagExpr{Tag: parts[n-1]})
+		case knownOS[parts[n-1]] || knownArch[parts[n-1]]:
+			and(&constraint.TagExpr{Tag: parts[n-1]})
+		}
+	}
+
+	// Build constraints must appear before the package clause
+	var goBuild, plusBuild []constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			x, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+
+			if constraint.IsGoBuild(comment.Text) {
+				goBuild = append(goBuild, x)
+			} else {
+				plusBuild = append(plusBuild, x)
+			}
+		}
+	}
+
+	if len(goBuild) == 0 {
+		goBuild = plusBuild
+	}
+
+	for _, x := range goBuild {
+		and(x)
+	}
+
+	return expr
+}
+
+// buildTags returns the tags of a build constraint.
+// Negated tags are prefixed with "!" (e.g. !cgo).
+func buildTags(expr constraint.Expr) []string {
+	return appendConstraintTags([]string{}, expr, false)
+}
+
+func appendConstraintTags(tags []string, expr constraint.Expr, negated bool) []string {
+	switch v := expr.(type) {
+	case *constraint.TagExpr:
+		if negated {
+			return append(tags, "!"+v.Tag)
+		}
+		return append(tags, v.Tag)
+	case *constraint.NotExpr:
+		return appendConstraintTags(tags, v.X, !negated)
+	case *constraint.AndExpr:
+		return appendConstraintTags(appendConstraintTags(tags, v.X, negated), v.Y, negated)
+	case *constraint.OrExpr:
+		return appendConstraintTags(appendConstraintTags(tags, v.X, negated), v.Y, negated)
+	}
+
+	return tags
+}
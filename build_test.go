@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"testing"
+
+	goparser "go/parser"
+	gotoken "go/token"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	tests := []struct {
+		name         string
+		filename     string
+		src          string
+		expectedExpr string
+		expectedTags []string
+	}{
+		{
+			name:         "NoConstraint",
+			filename:     "lookup.go",
+			src:          "package lookup\n",
+			expectedExpr: "",
+			expectedTags: []string{},
+		},
+		{
+			name:         "NotPlatform",
+			filename:     "lookup_service.go",
+			src:          "package lookup\n",
+			expectedExpr: "",
+			expectedTags: []string{},
+		},
+		{
+			name:         "FileNameOS",
+			filename:     "lookup_windows.go",
+			src:          "package lookup\n",
+			expectedExpr: "windows",
+			expectedTags: []string{"windows"},
+		},
+		{
+			name:         "FileNameOS_zOS",
+			filename:     "lookup_zos.go",
+			src:          "package lookup\n",
+			expectedExpr: "zos",
+			expectedTags: []string{"zos"},
+		},
+		{
+			name:         "FileNameArch",
+			filename:     "lookup_sparc64.go",
+			src:          "package lookup\n",
+			expectedExpr: "sparc64",
+			expectedTags: []string{"sparc64"},
+		},
+		{
+			name:         "FileNameOSArch",
+			filename:     "lookup_linux_arm64_test.go",
+			src:          "package lookup\n",
+			expectedExpr: "linux && arm64",
+			expectedTags: []string{"linux", "arm64"},
+		},
+		{
+			name:         "FileNameAndGoBuild",
+			filename:     "lookup_linux.go",
+			src:          "//go:build amd64 || arm64\n\npackage lookup\n",
+			expectedExpr: "linux && (amd64 || arm64)",
+			expectedTags: []string{"linux", "amd64", "arm64"},
+		},
+		{
+			name:         "GoBuild",
+			filename:     "lookup.go",
+			src:          "// Copyright\n\n//go:build (linux || darwin) && !cgo\n\npackage lookup\n\n//go:build ignored\n",
+			expectedExpr: "(linux || darwin) && !cgo",
+			expectedTags: []string{"linux", "darwin", "!cgo"},
+		},
+		{
+			name:         "PlusBuild",
+			filename:     "lookup.go",
+			src:          "// +build linux darwin\n// +build !cgo\n\npackage lookup\n",
+			expectedExpr: "(linux || darwin) && !cgo",
+			expectedTags: []string{"linux", "darwin", "!cgo"},
+		},
+		{
+			name:         "GoBuildAndPlusBuild",
+			filename:     "lookup.go",
+			src:          "//go:build windows\n// +build linux\n\npackage lookup\n",
+			expectedExpr: "windows",
+			expectedTags: []string{"windows"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), tc.filename, tc.src, goparser.ParseComments)
+			assert.NoError(t, err)
+
+			expr := buildConstraint(tc.filename, file)
+
+			if tc.expectedExpr == "" {
+				assert.Nil(t, expr)
+			} else {
+				assert.Equal(t, tc.expectedExpr, expr.String())
+			}
+
+			assert.Equal(t, tc.expectedTags, buildTags(expr))
+		})
+	}
+}
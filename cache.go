@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	goast "go/ast"
+)
+
+// ParseCache is used for caching parsed Go source code files.
+// Cached files are shared across parses and must not be modified by consumers (see ParseOptions.CopyCachedFiles).
+type ParseCache interface {
+	// Get returns a parsed file if it is cached with the same modification time.
+	Get(path string, modTime time.Time) (*goast.File, bool)
+	// Put caches a parsed file with its modification time.
+	Put(path string, modTime time.Time, f *goast.File)
+}
+
+type cacheEntry struct {
+	modTime time.Time
+	file    *goast.File
+}
+
+// MemoryCache is an in-memory implementation of ParseCache.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewMemoryCache creates a new in-memory cache for parsed files.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns a parsed file if it is cached with the same modification time.
+func (c *MemoryCache) Get(path string, modTime time.Time) (*goast.File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[path]
+	if !ok || !e.modTime.Equal(modTime) {
+		return nil, false
+	}
+
+	return e.file, true
+}
+
+// Put caches a parsed file with its modification time.
+func (c *MemoryCache) Put(path string, modTime time.Time, f *goast.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[path] = cacheEntry{
+		modTime: modTime,
+		file:    f,
+	}
+}
+
+// copyFile returns a deep copy of a parsed file.
+// It is only used for caching when ParseOptions.CopyCachedFiles is set.
+// Nodes shared within the file (e.g. comment groups referenced by both a declaration and the file) remain shared in the copy.
+func copyFile(f *goast.File) *goast.File {
+	c := &astCopier{
+		copies: make(map[any]reflect.Value),
+	}
+
+	return c.copy(reflect.ValueOf(f)).Interface().(*goast.File)
+}
+
+// astCopier deep copies syntax trees using reflection.
+type astCopier struct {
+	// copies maps the pointers and maps already copied to their copies, so shared references and cycles (e.g. objects and scopes) are preserved.
+	copies map[any]reflect.Value
+}
+
+func (c *astCopier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+
+		key := v.Interface()
+		if cp, ok := c.copies[key]; ok {
+			return cp
+		}
+
+		cp := reflect.New(v.Type().Elem())
+		c.copies[key] = cp
+		cp.Elem().Set(c.copy(v.Elem()))
+
+		return cp
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(c.copy(v.Elem()))
+
+		return cp
+
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if cp.Field(i).CanSet() {
+				cp.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+
+		return cp
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(c.copy(v.Index(i)))
+		}
+
+		return cp
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		key := v.UnsafePointer()
+		if cp, ok := c.copies[key]; ok {
+			return cp
+		}
+
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.copies[key] = cp
+		for iter := v.MapRange(); iter.Next(); {
+			cp.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+
+		return cp
+	}
+
+	return v
+}
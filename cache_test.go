@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache(t *testing.T) {
+	now := time.Now()
+	file := &goast.File{
+		Name: &goast.Ident{Name: "main"},
+	}
+
+	c := NewMemoryCache()
+
+	f, ok := c.Get("main.go", now)
+	assert.False(t, ok)
+	assert.Nil(t, f)
+
+	c.Put("main.go", now, file)
+
+	f, ok = c.Get("main.go", now)
+	assert.True(t, ok)
+	assert.Equal(t, file, f)
+
+	f, ok = c.Get("main.go", now.Add(time.Second))
+	assert.False(t, ok)
+	assert.Nil(t, f)
+}
+
+type countingCache struct {
+	*MemoryCache
+	hits int
+}
+
+func (c *countingCache) Get(path string, modTime time.Time) (*goast.File, bool) {
+	f, ok := c.MemoryCache.Get(path, modTime)
+	if ok {
+		c.hits++
+	}
+	return f, ok
+}
+
+func TestParser_Parse_Cache(t *testing.T) {
+	cache := &countingCache{
+		MemoryCache: NewMemoryCache(),
+	}
+
+	files := map[string]*goast.File{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(f *File, file *goast.File) bool {
+					files[f.ImportPath+"/"+f.Name] = file
+					return true
+				},
+			},
+		},
+	}
+
+	opts := ParseOptions{
+		Cache: cache,
+	}
+
+	assert.NoError(t, p.Parse("./test/valid/...", opts))
+	assert.Equal(t, 0, cache.hits)
+	first := maps.Clone(files)
+
+	assert.NoError(t, p.Parse("./test/valid/...", opts))
+	assert.Equal(t, len(cache.entries), cache.hits)
+
+	// Cached files are passed to consumers as they are
+	for name, file := range files {
+		assert.Same(t, first[name], file)
+	}
+}
+
+func TestParser_Parse_CacheModified(t *testing.T) {
+	decls := map[string]int{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "modifier",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(*File, *goast.File) bool { return true },
+				FilePost: func(f *File, file *goast.File) error {
+					decls[f.ImportPath+"/"+f.Name] = len(file.Decls)
+					file.Decls = append(file.Decls, &goast.GenDecl{})
+					return nil
+				},
+			},
+		},
+	}
+
+	opts := ParseOptions{
+		Cache:           NewMemoryCache(),
+		CopyCachedFiles: true,
+	}
+
+	assert.NoError(t, p.Parse("./test/valid/...", opts))
+	expected := maps.Clone(decls)
+
+	assert.NoError(t, p.Parse("./test/valid/...", opts))
+	assert.NotEmpty(t, decls)
+	assert.Equal(t, expected, decls)
+}
+
+func TestCopyFile(t *testing.T) {
+	fset := gotoken.NewFileSet()
+	src := "package main\n\n// Request is a request.\ntype Request struct{}\n\nfunc main() {\n\tr := Request{}\n\t_ = r\n}\n"
+	file, err := goparser.ParseFile(fset, "main.go", src, goparser.ParseComments)
+	assert.NoError(t, err)
+
+	cp := copyFile(file)
+	assert.Equal(t, file, cp)
+	assert.NotSame(t, file, cp)
+	assert.NotSame(t, file.Decls[0], cp.Decls[0])
+
+	// Shared nodes remain shared
+	assert.Same(t, cp.Comments[0], cp.Decls[0].(*goast.GenDecl).Doc)
+	assert.Same(t, cp.Scope.Lookup("Request").Decl, cp.Decls[0].(*goast.GenDecl).Specs[0])
+
+	cp.Decls = append(cp.Decls, &goast.GenDecl{})
+	cp.Name.Name = "lookup"
+	assert.Len(t, file.Decls, 2)
+	assert.Equal(t, "main", file.Name.Name)
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"sort"
+
+	goast "go/ast"
+)
+
+// CallGraph keeps track of function calls within packages.
+// Functions are identified by their fully-qualified names (e.g. github.com/octocat/test/lookup.New).
+// Methods are identified by their receiver type names (e.g. github.com/octocat/test/lookup.service.Lookup).
+// Functions in external test packages are qualified by the package ID (e.g. github.com/octocat/test/lookup_test.TestNew),
+// so they never collide with the functions of the package they test.
+type CallGraph struct {
+	funcs   map[string]bool
+	callees map[string]map[string]bool
+}
+
+// NewCallGraphConsumer creates a new consumer that records call graph edges between functions in the same package.
+// A call is resolved to a function in the same package if it is made using a bare identifier (e.g. lookup()).
+func NewCallGraphConsumer() (*Consumer, *CallGraph) {
+	g := &CallGraph{
+		funcs:   make(map[string]bool),
+		callees: make(map[string]map[string]bool),
+	}
+
+	c := &Consumer{
+		Name:     "call-graph",
+		Package:  func(*Package, string) bool { return true },
+		FilePre:  func(*File, *goast.File) bool { return true },
+		FuncDecl: g.funcDecl,
+	}
+
+	return c, g
+}
+
+func (g *CallGraph) funcDecl(f *Func, _ *goast.FuncType, body *goast.BlockStmt) {
+	pkg := f.symbolPath()
+
+	caller := pkg + "." + f.Name
+	if f.IsMethod() {
+		caller = pkg + "." + f.ReceiverTypeName() + "." + f.Name
+	} else {
+		g.funcs[caller] = true
+	}
+
+	if body == nil {
+		return
+	}
+
+	goast.Inspect(body, func(n goast.Node) bool {
+		if call, ok := n.(*goast.CallExpr); ok {
+			if id, ok := call.Fun.(*goast.Ident); ok {
+				if _, ok := g.callees[caller]; !ok {
+					g.callees[caller] = make(map[string]bool)
+				}
+				g.callees[caller][pkg+"."+id.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// Callees returns the sorted list of functions in the same package called by a given function.
+func (g *CallGraph) Callees(name string) []string {
+	callees := []string{}
+	for callee := range g.callees[name] {
+		// Ignore calls to builtin functions, local variables, etc.
+		if g.funcs[callee] {
+			callees = append(callees, callee)
+		}
+	}
+
+	sort.Strings(callees)
+
+	return callees
+}
+
+// Callers returns the sorted list of functions and methods in the same package calling a given function.
+func (g *CallGraph) Callers(name string) []string {
+	callers := []string{}
+	if !g.funcs[name] {
+		return callers
+	}
+
+	for caller, callees := range g.callees {
+		if callees[name] {
+			callers = append(callers, caller)
+		}
+	}
+
+	sort.Strings(callers)
+
+	return callers
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"testing"
+
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const callGraphSrc = `package calls
+
+func a() {
+	b()
+	c()
+	_ = len("builtin")
+}
+
+func b() {
+	c()
+}
+
+func c() {
+	fn := func() {}
+	fn()
+}
+
+type s struct{}
+
+func (v *s) m() {
+	a()
+}
+
+type list[T any] struct{}
+
+func (l *list[T]) push(v T) {
+	b()
+}
+`
+
+const callGraphTestSrc = `package calls_test
+
+func a() {
+	c()
+}
+
+func c() {}
+`
+
+func TestCallGraph(t *testing.T) {
+	fset := gotoken.NewFileSet()
+
+	c, g := NewCallGraphConsumer()
+	assert.NotNil(t, c)
+	assert.NotNil(t, g)
+
+	for _, src := range []string{callGraphSrc, callGraphTestSrc} {
+		file, err := goparser.ParseFile(fset, "calls.go", src, goparser.SkipObjectResolution)
+		assert.NoError(t, err)
+
+		for _, decl := range file.Decls {
+			if v, ok := decl.(*goast.FuncDecl); ok {
+				f := &Func{
+					File: File{
+						Package: Package{Name: file.Name.Name, ImportPath: "calls"},
+					},
+					Name: v.Name.Name,
+				}
+
+				if v.Recv != nil {
+					f.RecvName = v.Recv.List[0].Names[0].Name
+					f.RecvType = v.Recv.List[0].Type
+				}
+
+				c.FuncDecl(f, v.Type, v.Body)
+			}
+		}
+	}
+
+	tests := []struct {
+		name            string
+		expectedCallees []string
+		expectedCallers []string
+	}{
+		{
+			name:            "calls.a",
+			expectedCallees: []string{"calls.b", "calls.c"},
+			expectedCallers: []string{"calls.s.m"},
+		},
+		{
+			name:            "calls.b",
+			expectedCallees: []string{"calls.c"},
+			expectedCallers: []string{"calls.a", "calls.list.push"},
+		},
+		{
+			name:            "calls.c",
+			expectedCallees: []string{},
+			expectedCallers: []string{"calls.a", "calls.b"},
+		},
+		{
+			name:            "calls.s.m",
+			expectedCallees: []string{"calls.a"},
+			expectedCallers: []string{},
+		},
+		{
+			name:            "calls.list.push",
+			expectedCallees: []string{"calls.b"},
+			expectedCallers: []string{},
+		},
+		{
+			name:            "calls_test.a",
+			expectedCallees: []string{"calls_test.c"},
+			expectedCallers: []string{},
+		},
+		{
+			name:            "calls_test.c",
+			expectedCallees: []string{},
+			expectedCallers: []string{"calls_test.a"},
+		},
+		{
+			name:            "calls.len",
+			expectedCallees: []string{},
+			expectedCallers: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedCallees, g.Callees(tc.name))
+			assert.Equal(t, tc.expectedCallers, g.Callers(tc.name))
+		})
+	}
+}
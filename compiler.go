@@ -1,6 +1,14 @@
 package parser
 
-import "github.com/gardenbed/charm/ui"
+import (
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/gardenbed/charm/ui"
+)
 
 // Compiler is used for parsing Go source code files and compiling new source code files.
 type Compiler struct {
@@ -9,16 +17,97 @@ type Compiler struct {
 
 // NewCompiler creates a new compiler.
 // This is meant to be used by downstream packages that provide consumers.
+// Calls to the UI are serialized, so the compiler can be used concurrently with a UI that is not concurrent-safe.
 func NewCompiler(ui ui.UI, consumers ...*Consumer) *Compiler {
 	return &Compiler{
 		parser: &parser{
-			ui:        ui,
+			ui:        newLockedUI(ui),
 			consumers: consumers,
+			sources:   newSourcePool(),
 		},
 	}
 }
 
+// NewCompilerWithVisitor creates a new compiler using visitors instead of consumers.
+// This is meant to be used by downstream packages that provide visitors.
+// A visitor is registered under the name returned by its Name method if it has one, or its type name otherwise.
+// Duplicate names are suffixed with an index (e.g. "*pkg.visitor#2"), so each visitor can be removed individually.
+func NewCompilerWithVisitor(ui ui.UI, visitors ...Visitor) *Compiler {
+	consumers := make([]*Consumer, len(visitors))
+	seen := map[string]int{}
+	for i, v := range visitors {
+		name := visitorName(v)
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+		consumers[i] = visitorConsumer(name, v)
+	}
+
+	return NewCompiler(ui, consumers...)
+}
+
+// AddConsumer registers a new consumer that runs after the existing ones.
+// It is safe to call concurrently (even from consumers), and it only affects the compilations started afterwards.
+func (c *Compiler) AddConsumer(consumer *Consumer) {
+	c.parser.addConsumer(consumer)
+}
+
+// RemoveConsumer unregisters all consumers with a given name.
+// It is safe to call concurrently (even from consumers), and it only affects the compilations started afterwards.
+func (c *Compiler) RemoveConsumer(name string) {
+	c.parser.removeConsumer(name)
+}
+
+// Reset discards the state retained across compilations.
+// When a parse cache is used, a file set is shared across compilations and grows with every parsed file.
+// Resetting the compiler releases the file set, so any cache used with the compiler must be discarded too.
+// Buffers for reading source code files are pooled across compilations and are released by the garbage collector.
+// File sets are never pooled, since consumers may keep using them after a compilation (e.g. for writing files).
+// It is safe to call concurrently (even from consumers), and it only affects the compilations started afterwards.
+func (c *Compiler) Reset() {
+	c.parser.reset()
+}
+
 // Compile parses all Go source code files in a given path and generates new artifacts (source codes).
 func (c *Compiler) Compile(path string, opts ParseOptions) error {
 	return c.parser.Parse(path, opts)
 }
+
+// CompileAll parses all Go source code files in the given paths and generates new artifacts (source codes).
+// The paths are parsed in a single run, so consumers see the union of all packages in the paths.
+// A package directory reachable from multiple paths is parsed only once.
+func (c *Compiler) CompileAll(paths []string, opts ParseOptions) error {
+	return c.parser.ParseAll(paths, opts)
+}
+
+// CompileFS parses all Go source code files in a given path of a file system and generates new artifacts (source codes).
+// Paths are slash-separated and relative to the root of the file system (e.g. "internal/...").
+// The go.mod file is also looked up through the file system.
+func (c *Compiler) CompileFS(fsys fs.FS, path string, opts ParseOptions) error {
+	return c.parser.ParseFS(fsys, path, opts)
+}
+
+// CompileImportPath parses all Go source code files of a package specified by its import path.
+// If the import path ends with "/...", all subdirectories will be considered too.
+// The package directory is located using the go/build package relative to the current working directory,
+// so module import paths are resolved using the go command.
+func (c *Compiler) CompileImportPath(importPath string, opts ParseOptions) error {
+	importPath, recursive := strings.CutSuffix(importPath, "/...")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	pkg, err := build.Import(importPath, wd, build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("cannot locate package %q: %s", importPath, err)
+	}
+
+	path := pkg.Dir
+	if recursive {
+		path += "/..."
+	}
+
+	return c.parser.Parse(path, opts)
+}
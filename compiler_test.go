@@ -2,9 +2,14 @@ package parser
 
 import (
 	"errors"
+	"io/fs"
+	"os"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	goast "go/ast"
+	gotoken "go/token"
 
 	"github.com/gardenbed/charm/ui"
 	"github.com/stretchr/testify/assert"
@@ -29,12 +34,120 @@ func TestNewCompiler(t *testing.T) {
 
 			assert.NotNil(t, c)
 			assert.NotNil(t, c.parser)
-			assert.Equal(t, tc.ui, c.parser.ui)
+			assert.Equal(t, &lockedUI{ui: tc.ui}, c.parser.ui)
 			assert.Equal(t, tc.consumers, c.parser.consumers)
 		})
 	}
 }
 
+func TestCompiler_AddConsumer(t *testing.T) {
+	c1 := &Consumer{Name: "first"}
+	c2 := &Consumer{Name: "second"}
+
+	c := NewCompiler(ui.NewNop(), c1)
+	c.AddConsumer(c2)
+
+	assert.Equal(t, []*Consumer{c1, c2}, c.parser.consumers)
+}
+
+func TestCompiler_RemoveConsumer(t *testing.T) {
+	tests := []struct {
+		name              string
+		consumers         []*Consumer
+		consumerName      string
+		expectedConsumers []*Consumer
+	}{
+		{
+			name:              "NotFound",
+			consumers:         []*Consumer{{Name: "first"}},
+			consumerName:      "second",
+			expectedConsumers: []*Consumer{{Name: "first"}},
+		},
+		{
+			name:              "Found",
+			consumers:         []*Consumer{{Name: "first"}, {Name: "second"}, {Name: "first"}},
+			consumerName:      "first",
+			expectedConsumers: []*Consumer{{Name: "second"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCompiler(ui.NewNop(), tc.consumers...)
+			c.RemoveConsumer(tc.consumerName)
+
+			assert.Equal(t, tc.expectedConsumers, c.parser.consumers)
+		})
+	}
+}
+
+func TestCompiler_Reset(t *testing.T) {
+	c := NewCompiler(ui.NewNop(), &Consumer{
+		Name:    "tester",
+		Package: func(*Package, string) bool { return true },
+		FilePre: func(*File, *goast.File) bool { return true },
+	})
+
+	err := c.Compile("./test/valid/...", ParseOptions{Cache: NewMemoryCache()})
+	assert.NoError(t, err)
+	assert.NotNil(t, c.parser.fset)
+
+	c.Reset()
+	assert.Nil(t, c.parser.fset)
+
+	err = c.Compile("./test/valid/...", ParseOptions{Cache: NewMemoryCache()})
+	assert.NoError(t, err)
+	assert.NotNil(t, c.parser.fset)
+}
+
+func TestCompiler_Concurrent(t *testing.T) {
+	t.Run("Cache", func(t *testing.T) {
+		c := NewCompiler(ui.NewNop(), &Consumer{
+			Name:    "tester",
+			Package: func(*Package, string) bool { return true },
+			FilePre: func(*File, *goast.File) bool { return true },
+		})
+
+		cache := NewMemoryCache()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, c.Compile("./test/valid/...", ParseOptions{Cache: cache}))
+			}()
+		}
+		wg.Wait()
+
+		assert.NotNil(t, c.parser.fset)
+	})
+
+	t.Run("ChangedByConsumer", func(t *testing.T) {
+		c := NewCompiler(ui.NewNop())
+
+		packages := 0
+		c.AddConsumer(&Consumer{
+			Name: "tester",
+			Package: func(*Package, string) bool {
+				packages++
+
+				// Changes only affect the compilations started afterwards
+				c.AddConsumer(&Consumer{Name: "other"})
+				c.RemoveConsumer("other")
+				c.Reset()
+
+				return false
+			},
+		})
+
+		err := c.Compile("./test/valid/...", ParseOptions{SkipTestFiles: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, packages)
+	})
+}
+
 func TestCompiler_Compile(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -83,7 +196,10 @@ func TestCompiler_Compile(t *testing.T) {
 					Struct:    func(*Type, *goast.StructType) {},
 					Interface: func(*Type, *goast.InterfaceType) {},
 					FuncType:  func(*Type, *goast.FuncType) {},
+					Alias:     func(*Type, goast.Expr) {},
+					Named:     func(*Type, goast.Expr) {},
 					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
+					Directive: func(*File, string, []string, gotoken.Position) {},
 					FilePost:  func(*File, *goast.File) error { return nil },
 				},
 			},
@@ -104,7 +220,10 @@ func TestCompiler_Compile(t *testing.T) {
 					Struct:    func(*Type, *goast.StructType) {},
 					Interface: func(*Type, *goast.InterfaceType) {},
 					FuncType:  func(*Type, *goast.FuncType) {},
+					Alias:     func(*Type, goast.Expr) {},
+					Named:     func(*Type, goast.Expr) {},
 					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
+					Directive: func(*File, string, []string, gotoken.Position) {},
 					FilePost:  func(*File, *goast.File) error { return errors.New("file error") },
 				},
 			},
@@ -112,7 +231,7 @@ func TestCompiler_Compile(t *testing.T) {
 			opts: ParseOptions{
 				SkipTestFiles: true,
 			},
-			expectedError: "file error",
+			expectedError: `consumer "tester" failed on main.go: file error`,
 		},
 	}
 
@@ -131,3 +250,254 @@ func TestCompiler_Compile(t *testing.T) {
 		})
 	}
 }
+
+func TestCompiler_CompileFS(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"go.mod":           {Data: []byte("module github.com/octocat/test\n")},
+		"main.go":          {Data: []byte("package main\n\nfunc main() {}\n")},
+		"lookup/lookup.go": {Data: []byte("package lookup\n\ntype Request struct{}\n")},
+		"invalid/main.go":  {Data: []byte("package main\n\nfunc main {}\n")},
+	}
+
+	tests := []struct {
+		name          string
+		fsys          fs.FS
+		path          string
+		expectedTypes []string
+		expectedError string
+	}{
+		{
+			name:          "PathNotExist",
+			fsys:          mapFS,
+			path:          "foo",
+			expectedError: "open foo: file does not exist",
+		},
+		{
+			name:          "InvalidCode",
+			fsys:          mapFS,
+			path:          "invalid",
+			expectedError: "invalid/main.go:3:11: expected '(', found '{' (and 6 more errors)",
+		},
+		{
+			name:          "Success_MapFS",
+			fsys:          mapFS,
+			path:          "lookup/...",
+			expectedTypes: []string{"github.com/octocat/test/lookup.Request"},
+		},
+		{
+			name:          "Success_DirFS",
+			fsys:          os.DirFS("./test/valid"),
+			path:          "./...",
+			expectedTypes: []string{"github.com/octocat/test/lookup.Request", "github.com/octocat/test/lookup.Response", "github.com/octocat/test/lookup.service"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			types := []string{}
+
+			c := NewCompiler(ui.NewNop(), &Consumer{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(f *File, _ *goast.File) bool { return !f.IsTest },
+				Struct: func(t *Type, _ *goast.StructType) {
+					types = append(types, t.ImportPath+"."+t.Name)
+				},
+			})
+
+			err := c.CompileFS(tc.fsys, tc.path, ParseOptions{})
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedTypes, types)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func BenchmarkCompiler_Compile(b *testing.B) {
+	consumer := &Consumer{
+		Name:     "bench",
+		Package:  func(*Package, string) bool { return true },
+		FilePre:  func(*File, *goast.File) bool { return true },
+		FuncDecl: func(*Func, *goast.FuncType, *goast.BlockStmt) {},
+	}
+
+	b.Run("NoCache", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := c.Compile("./test/valid/...", ParseOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("MemoryCache", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		cache := NewMemoryCache()
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := c.Compile("./test/valid/...", ParseOptions{Cache: cache}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("MemoryCache_Reset", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			c.Reset()
+			if err := c.Compile("./test/valid/...", ParseOptions{Cache: NewMemoryCache()}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("MemoryCache_CopyCachedFiles", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		cache := NewMemoryCache()
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := c.Compile("./test/valid/...", ParseOptions{Cache: cache, CopyCachedFiles: true}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCompiler_Compile_Pooling compiles the package of this repository,
+// so the pooled buffers for reading source code files make a difference.
+func BenchmarkCompiler_Compile_Pooling(b *testing.B) {
+	consumer := &Consumer{
+		Name:    "bench",
+		Package: func(*Package, string) bool { return true },
+		FilePre: func(*File, *goast.File) bool { return true },
+	}
+
+	b.Run("NoPooling", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		c.parser.sources = nil
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := c.Compile(".", ParseOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Pooling", func(b *testing.B) {
+		c := NewCompiler(ui.NewNop(), consumer)
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := c.Compile(".", ParseOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCompiler_CompileImportPath(t *testing.T) {
+	tests := []struct {
+		name             string
+		importPath       string
+		expectedPackages []string
+		expectedError    string
+	}{
+		{
+			name:          "NotFound",
+			importPath:    "github.com/gardenbed/go-parser/foo",
+			expectedError: `cannot locate package "github.com/gardenbed/go-parser/foo"`,
+		},
+		{
+			name:             "Success",
+			importPath:       "github.com/gardenbed/go-parser",
+			expectedPackages: []string{"github.com/gardenbed/go-parser"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			packages := []string{}
+
+			c := NewCompiler(ui.NewNop(), &Consumer{
+				Name: "tester",
+				Package: func(p *Package, _ string) bool {
+					packages = append(packages, p.ImportPath)
+					return false
+				},
+			})
+
+			err := c.CompileImportPath(tc.importPath, ParseOptions{SkipTestFiles: true})
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPackages, packages)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestCompiler_CompileAll(t *testing.T) {
+	tests := []struct {
+		name             string
+		paths            []string
+		expectedPackages []string
+		expectedError    string
+	}{
+		{
+			name:          "PathNotExist",
+			paths:         []string{"./test/valid", "./test/foo"},
+			expectedError: "stat ./test/foo: no such file or directory",
+		},
+		{
+			name:             "Success",
+			paths:            []string{"./test/valid/...", "./test/nested"},
+			expectedPackages: []string{"github.com/octocat/test", "github.com/octocat/test/lookup", "github.com/octocat/nested"},
+		},
+		{
+			name:             "Success_Overlapping",
+			paths:            []string{"./test/valid/...", "test/valid", "./test/valid/..."},
+			expectedPackages: []string{"github.com/octocat/test", "github.com/octocat/test/lookup"},
+		},
+		{
+			name:             "NestedPath",
+			paths:            []string{"./test/valid/lookup", "./test/valid"},
+			expectedPackages: []string{"github.com/octocat/test/lookup", "github.com/octocat/test"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			packages := []string{}
+
+			c := NewCompiler(ui.NewNop(), &Consumer{
+				Name: "tester",
+				Package: func(p *Package, _ string) bool {
+					packages = append(packages, p.ImportPath)
+					return false
+				},
+			})
+
+			err := c.CompileAll(tc.paths, ParseOptions{SkipTestFiles: true})
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPackages, packages)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
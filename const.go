@@ -0,0 +1,306 @@
+package parser
+
+import (
+	"fmt"
+
+	goast "go/ast"
+	"go/constant"
+	gotoken "go/token"
+)
+
+// ConstValue contains information about an evaluated constant.
+type ConstValue struct {
+	Name  string
+	Value constant.Value
+	// Type is the explicit type of the constant and nil for untyped constants.
+	Type goast.Expr
+}
+
+// EvalConstValues evaluates the values of all constants declared in a const declaration.
+// It resolves iota, the implicit repetition of the previous expression list, references to the constants
+// declared earlier in the same declaration, conversions (e.g. Weekday(iota) or float64(1)), constant built-in
+// function calls (e.g. len("abc")), and arithmetic on them.
+// Any other expression, such as a reference to a constant declared elsewhere, results in an error.
+func EvalConstValues(decl *goast.GenDecl) ([]ConstValue, error) {
+	if decl.Tok != gotoken.CONST {
+		return nil, fmt.Errorf("%s is not a const declaration", decl.Tok)
+	}
+
+	e := &constEvaluator{
+		consts: make(map[string]constant.Value),
+	}
+
+	values := []ConstValue{}
+
+	var prevValues []goast.Expr
+	var prevType goast.Expr
+
+	for i, spec := range decl.Specs {
+		vs, ok := spec.(*goast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		// An empty expression list repeats the previous expression list and type
+		exprs, typ := vs.Values, vs.Type
+		if len(exprs) == 0 {
+			exprs, typ = prevValues, prevType
+		}
+
+		if len(exprs) != len(vs.Names) {
+			return nil, fmt.Errorf("constant %s: expected %d values, found %d", vs.Names[0].Name, len(vs.Names), len(exprs))
+		}
+
+		e.iota = int64(i)
+		for j, name := range vs.Names {
+			val, err := e.eval(exprs[j])
+			if err != nil {
+				return nil, fmt.Errorf("constant %s: %s", name.Name, err)
+			}
+
+			if name.Name != "_" {
+				e.consts[name.Name] = val
+			}
+
+			values = append(values, ConstValue{
+				Name:  name.Name,
+				Value: val,
+				Type:  typ,
+			})
+		}
+
+		prevValues, prevType = exprs, typ
+	}
+
+	return values, nil
+}
+
+// constEvaluator keeps track of the state for evaluating a const declaration.
+type constEvaluator struct {
+	iota   int64
+	consts map[string]constant.Value
+}
+
+func (e *constEvaluator) eval(expr goast.Expr) (constant.Value, error) {
+	switch v := expr.(type) {
+	case *goast.BasicLit:
+		if val := constant.MakeFromLiteral(v.Value, v.Kind, 0); val.Kind() != constant.Unknown {
+			return val, nil
+		}
+
+	case *goast.Ident:
+		switch v.Name {
+		case "iota":
+			return constant.MakeInt64(e.iota), nil
+		case "true":
+			return constant.MakeBool(true), nil
+		case "false":
+			return constant.MakeBool(false), nil
+		}
+
+		if val, ok := e.consts[v.Name]; ok {
+			return val, nil
+		}
+
+		return nil, fmt.Errorf("undefined: %s", v.Name)
+
+	case *goast.ParenExpr:
+		return e.eval(v.X)
+
+	case *goast.CallExpr:
+		return e.evalCall(v)
+
+	case *goast.UnaryExpr:
+		x, err := e.eval(v.X)
+		if err != nil {
+			return nil, err
+		}
+		return constantOp(func() constant.Value {
+			return constant.UnaryOp(v.Op, x, 0)
+		})
+
+	case *goast.BinaryExpr:
+		x, err := e.eval(v.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := e.eval(v.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return binaryOp(x, v.Op, y)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %s", exprString(expr))
+}
+
+// evalCall evaluates a call to a built-in function or a conversion.
+func (e *constEvaluator) evalCall(call *goast.CallExpr) (constant.Value, error) {
+	args := make([]constant.Value, len(call.Args))
+	for i, arg := range call.Args {
+		val, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+
+	name := ""
+	if id, ok := call.Fun.(*goast.Ident); ok {
+		name = id.Name
+	}
+
+	switch name {
+	case "len":
+		if len(args) == 1 && args[0].Kind() == constant.String {
+			return constant.MakeInt64(int64(len(constant.StringVal(args[0])))), nil
+		}
+
+	case "real", "imag":
+		if len(args) == 1 && isNumeric(args[0]) {
+			if name == "real" {
+				return constant.Real(args[0]), nil
+			}
+			return constant.Imag(args[0]), nil
+		}
+
+	case "complex":
+		if len(args) == 2 && isNumeric(args[0]) && isNumeric(args[1]) {
+			return constantOp(func() constant.Value {
+				im := constant.BinaryOp(args[1], gotoken.MUL, constant.MakeImag(constant.MakeInt64(1)))
+				return constant.BinaryOp(args[0], gotoken.ADD, im)
+			})
+		}
+
+	case "min", "max":
+		if len(args) == 0 {
+			break
+		}
+
+		op := gotoken.LSS
+		if name == "max" {
+			op = gotoken.GTR
+		}
+
+		res := args[0]
+		for _, arg := range args[1:] {
+			less, err := binaryOp(arg, op, res)
+			if err != nil {
+				return nil, err
+			}
+			if constant.BoolVal(less) {
+				res = arg
+			}
+		}
+
+		return res, nil
+
+	case "cap", "append", "clear", "close", "copy", "delete", "make", "new", "panic", "print", "println", "recover":
+		return nil, fmt.Errorf("%s is not a constant expression", exprString(call))
+
+	default:
+		// Conversions (e.g. float64(1), Weekday(iota), or time.Duration(1))
+		if len(args) == 1 {
+			return convert(name, args[0])
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported expression %s", exprString(call))
+}
+
+// convert converts a constant value to a predeclared type.
+// Values converted to any other type are returned as they are, since their underlying types are not known.
+func convert(typ string, val constant.Value) (constant.Value, error) {
+	var res constant.Value
+	var kind constant.Kind
+
+	switch typ {
+	case "float32", "float64":
+		res, kind = constant.ToFloat(val), constant.Float
+	case "complex64", "complex128":
+		res, kind = constant.ToComplex(val), constant.Complex
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+		res, kind = constant.ToInt(val), constant.Int
+	case "string":
+		res, kind = val, constant.String
+		// Converting an integer to a string yields the UTF-8 representation of the integer
+		if r, ok := constant.Int64Val(constant.ToInt(val)); ok && val.Kind() == constant.Int {
+			res = constant.MakeString(string(rune(r)))
+		}
+	default:
+		return val, nil
+	}
+
+	if res.Kind() != kind {
+		return nil, fmt.Errorf("cannot convert %s to type %s", val, typ)
+	}
+
+	return res, nil
+}
+
+func binaryOp(x constant.Value, op gotoken.Token, y constant.Value) (constant.Value, error) {
+	if op == gotoken.SHL || op == gotoken.SHR {
+		s, ok := constant.Uint64Val(y)
+		if !ok {
+			return nil, fmt.Errorf("invalid shift count %s", y)
+		}
+
+		return constantOp(func() constant.Value {
+			return constant.Shift(x, op, uint(s))
+		})
+	}
+
+	if !compatible(x, y) {
+		return nil, fmt.Errorf("mismatched types %s %s %s", x, op, y)
+	}
+
+	switch op {
+	case gotoken.EQL, gotoken.NEQ, gotoken.LSS, gotoken.LEQ, gotoken.GTR, gotoken.GEQ:
+		return constantOp(func() constant.Value {
+			return constant.MakeBool(constant.Compare(x, op, y))
+		})
+
+	case gotoken.QUO:
+		if y.Kind() == constant.Int && constant.Sign(y) == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+
+		// Integer division
+		if x.Kind() == constant.Int && y.Kind() == constant.Int {
+			op = gotoken.QUO_ASSIGN
+		}
+	}
+
+	return constantOp(func() constant.Value {
+		return constant.BinaryOp(x, op, y)
+	})
+}
+
+// compatible determines whether or not two constant values can be operands of the same binary operation.
+func compatible(x, y constant.Value) bool {
+	return x.Kind() == y.Kind() || isNumeric(x) && isNumeric(y)
+}
+
+// isNumeric determines whether or not a constant value is an integer, floating-point, or complex number.
+func isNumeric(v constant.Value) bool {
+	k := v.Kind()
+	return k == constant.Int || k == constant.Float || k == constant.Complex
+}
+
+// constantOp runs an operation on constant values and returns an error for invalid operations.
+// The go/constant package panics on invalid operations (e.g. adding a string to an integer).
+func constantOp(op func() constant.Value) (val constant.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			val, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	if val = op(); val.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("invalid operation")
+	}
+
+	return val, nil
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"testing"
+
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalConstValues(t *testing.T) {
+	tests := []struct {
+		name           string
+		src            string
+		expectedError  string
+		expectedValues []string
+	}{
+		{
+			name:          "NotConst",
+			src:           `var a = 1`,
+			expectedError: "var is not a const declaration",
+		},
+		{
+			name:          "Undefined",
+			src:           `const a = time.Second`,
+			expectedError: "constant a: unsupported expression time.Second",
+		},
+		{
+			name:          "UndefinedIdent",
+			src:           `const a = b + 1`,
+			expectedError: "constant a: undefined: b",
+		},
+		{
+			name:          "InvalidOperation",
+			src:           `const a = "a" + 1`,
+			expectedError: `constant a: mismatched types "a" + 1`,
+		},
+		{
+			name:          "DivisionByZero",
+			src:           `const a = 1 / 0`,
+			expectedError: "constant a: division by zero",
+		},
+		{
+			name:           "Single",
+			src:            `const Name = "lookup"`,
+			expectedValues: []string{`Name  "lookup"`},
+		},
+		{
+			name: "Iota",
+			src: `const (
+				Unknown Status = iota
+				Active
+				_
+				Inactive
+			)`,
+			expectedValues: []string{"Unknown Status 0", "Active Status 1", "_ Status 2", "Inactive Status 3"},
+		},
+		{
+			name: "IotaArithmetic",
+			src: `const (
+				_  = iota
+				KB = 1 << (10 * iota)
+				MB
+				Half = MB / 2 + KB - 1
+			)`,
+			expectedValues: []string{"_  0", "KB  1024", "MB  1048576", "Half  525311"},
+		},
+		{
+			name: "MultipleNames",
+			src: `const (
+				A, B = iota, iota * 10
+				C, D
+			)`,
+			expectedValues: []string{"A  0", "B  0", "C  1", "D  10"},
+		},
+		{
+			name: "Conversion",
+			src: `const (
+				Sunday = Weekday(iota + 1)
+				Monday
+				IsMonday = Monday == 2 && !false
+				Pi = 22 / 7.0
+			)`,
+			expectedValues: []string{"Sunday  1", "Monday  2", "IsMonday  true", "Pi  3.14286"},
+		},
+		{
+			name: "NumericConversion",
+			src: `const (
+				Half    = float64(1) / 2
+				Quarter = int(5) / 4
+				Imag    = complex128(2)
+				Letter  = string(65)
+			)`,
+			expectedValues: []string{"Half  0.5", "Quarter  1", "Imag  (2 + 0i)", `Letter  "A"`},
+		},
+		{
+			name:          "InvalidConversion",
+			src:           `const a = int(1.5)`,
+			expectedError: "constant a: cannot convert 1.5 to type int",
+		},
+		{
+			name: "Builtin",
+			src: `const (
+				Name   = "lookup"
+				Length = len(Name)
+				Re     = real(complex(1, 2))
+				Im     = imag(complex(1, 2))
+				Min    = min(3, 1, 2)
+				Max    = max(3, 1.5)
+			)`,
+			expectedValues: []string{`Name  "lookup"`, "Length  6", "Re  1", "Im  2", "Min  1", "Max  3"},
+		},
+		{
+			name:          "NonConstantBuiltin",
+			src:           `const a = cap(1)`,
+			expectedError: "constant a: cap(1) is not a constant expression",
+		},
+		{
+			name:          "InvalidBuiltin",
+			src:           `const a = len(1)`,
+			expectedError: "constant a: unsupported expression len(1)",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", "package main\n"+tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			values, err := EvalConstValues(file.Decls[0].(*goast.GenDecl))
+
+			if tc.expectedError != "" {
+				assert.Nil(t, values)
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+
+				strs := []string{}
+				for _, v := range values {
+					typ := ""
+					if v.Type != nil {
+						typ = exprString(v.Type)
+					}
+					strs = append(strs, v.Name+" "+typ+" "+v.Value.String())
+				}
+
+				assert.Equal(t, tc.expectedValues, strs)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// fileSystem abstracts the file system operations needed for parsing.
+type fileSystem interface {
+	Abs(name string) (string, error)
+	EvalSymlinks(name string) (string, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	Open(name string) (fs.File, error)
+}
+
+// osFileSystem implements the fileSystem interface using the operating system file system.
+type osFileSystem struct{}
+
+func (osFileSystem) Abs(name string) (string, error) {
+	return filepath.Abs(name)
+}
+
+func (osFileSystem) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+func (osFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// ioFileSystem implements the fileSystem interface using an fs.FS.
+// Paths are slash-separated and relative to the root of the fs.FS.
+// Symbolic links are not supported by fs.FS, so they are never resolved.
+type ioFileSystem struct {
+	fsys fs.FS
+}
+
+func (f ioFileSystem) Abs(name string) (string, error) {
+	return path.Clean(name), nil
+}
+
+func (f ioFileSystem) EvalSymlinks(name string) (string, error) {
+	return path.Clean(name), nil
+}
+
+func (f ioFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, path.Clean(name))
+}
+
+func (f ioFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, path.Clean(name))
+}
+
+func (f ioFileSystem) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, path.Clean(name))
+}
+
+func (f ioFileSystem) Open(name string) (fs.File, error) {
+	return f.fsys.Open(path.Clean(name))
+}
@@ -5,13 +5,13 @@ go 1.23.4
 require (
 	github.com/gardenbed/charm v0.1.4
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/mod v0.22.0
 	golang.org/x/tools v0.29.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/mod v0.22.0 // indirect
 	golang.org/x/sync v0.10.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,240 @@
+package parser
+
+import (
+	goast "go/ast"
+)
+
+// Implements determines whether or not a set of methods implements an interface.
+// Method signatures are compared using SignatureEqual, so no type checking is involved.
+// Embedded interfaces are not resolved and are ignored.
+// It returns the names of interface methods that are missing or have a different signature.
+func Implements(iface *goast.InterfaceType, methods []*Func) ([]string, bool) {
+	signatures := make(map[string]*goast.FuncType, len(methods))
+	for _, m := range methods {
+		if m.Type != nil {
+			signatures[m.Name] = m.Type
+		}
+	}
+
+	missing := []string{}
+	if iface.Methods != nil {
+		for _, field := range iface.Methods.List {
+			ft, ok := field.Type.(*goast.FuncType)
+			if !ok {
+				continue
+			}
+
+			for _, name := range field.Names {
+				if sig, ok := signatures[name.Name]; !ok || !SignatureEqual(sig, ft) {
+					missing = append(missing, name.Name)
+				}
+			}
+		}
+	}
+
+	return missing, len(missing) == 0
+}
+
+// Methods returns the methods declared in an interface type in their declaration order.
+// Embedded interfaces and type constraints are skipped.
+func Methods(it *goast.InterfaceType) []Method {
+	methods := []Method{}
+	if it.Methods == nil {
+		return methods
+	}
+
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*goast.FuncType)
+		if !ok {
+			continue
+		}
+
+		for _, name := range field.Names {
+			methods = append(methods, Method{
+				Name:    name.Name,
+				Params:  fieldParams(ft.Params),
+				Results: fieldParams(ft.Results),
+			})
+		}
+	}
+
+	return methods
+}
+
+// SignatureEqual determines whether or not two function types have the same signature.
+// Parameter and result names and formatting are ignored, so only the types, their order, and variadicity matter.
+// Type parameters are compared by their positions and constraints (e.g. func[T any](T) and func[U any](U) are equal).
+// Function types nested in parameters and results (e.g. callbacks) are compared the same way.
+// Types are compared as written, so no type checking is involved (e.g. an alias and its aliased type are different).
+func SignatureEqual(a, b *goast.FuncType) bool {
+	c := &typeComparer{
+		a: typeParamPositions(a),
+		b: typeParamPositions(b),
+	}
+
+	return c.funcTypesEqual(a, b)
+}
+
+// typeComparer compares type expressions of two function types.
+type typeComparer struct {
+	// a and b are the positions of the type parameters of each function type keyed by their names.
+	a, b map[string]int
+}
+
+// typeParamPositions returns the positions of the type parameters of a function type keyed by their names.
+func typeParamPositions(ft *goast.FuncType) map[string]int {
+	positions := map[string]int{}
+	if ft == nil || ft.TypeParams == nil {
+		return positions
+	}
+
+	i := 0
+	for _, field := range ft.TypeParams.List {
+		for _, name := range field.Names {
+			positions[name.Name] = i
+			i++
+		}
+	}
+
+	return positions
+}
+
+func (c *typeComparer) funcTypesEqual(a, b *goast.FuncType) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return c.fieldTypesEqual(a.TypeParams, b.TypeParams) &&
+		c.fieldTypesEqual(a.Params, b.Params) &&
+		c.fieldTypesEqual(a.Results, b.Results)
+}
+
+// fieldTypes returns the types of a field list with one entry per name.
+func fieldTypes(fields *goast.FieldList) []goast.Expr {
+	types := []goast.Expr{}
+	if fields == nil {
+		return types
+	}
+
+	for _, field := range fields.List {
+		// A field with no name is still counted once
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+
+		for i := 0; i < n; i++ {
+			types = append(types, field.Type)
+		}
+	}
+
+	return types
+}
+
+func (c *typeComparer) fieldTypesEqual(a, b *goast.FieldList) bool {
+	at, bt := fieldTypes(a), fieldTypes(b)
+	if len(at) != len(bt) {
+		return false
+	}
+
+	for i := range at {
+		if !c.typeEqual(at[i], bt[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// typeEqual compares two type expressions ignoring formatting, the parameter names of nested function types,
+// and the names of type parameters.
+func (c *typeComparer) typeEqual(a, b goast.Expr) bool {
+	for {
+		paren, ok := a.(*goast.ParenExpr)
+		if !ok {
+			break
+		}
+		a = paren.X
+	}
+
+	for {
+		paren, ok := b.(*goast.ParenExpr)
+		if !ok {
+			break
+		}
+		b = paren.X
+	}
+
+	switch x := a.(type) {
+	case *goast.Ident:
+		y, ok := b.(*goast.Ident)
+		if !ok {
+			return false
+		}
+
+		// Type parameters are matched by their positions
+		i, xParam := c.a[x.Name]
+		j, yParam := c.b[y.Name]
+		if xParam || yParam {
+			return xParam && yParam && i == j
+		}
+
+		return x.Name == y.Name
+
+	case *goast.FuncType:
+		y, ok := b.(*goast.FuncType)
+		return ok && c.funcTypesEqual(x, y)
+
+	case *goast.StarExpr:
+		y, ok := b.(*goast.StarExpr)
+		return ok && c.typeEqual(x.X, y.X)
+
+	case *goast.Ellipsis:
+		y, ok := b.(*goast.Ellipsis)
+		return ok && c.typeEqual(x.Elt, y.Elt)
+
+	case *goast.ArrayType:
+		y, ok := b.(*goast.ArrayType)
+		return ok && (x.Len == nil) == (y.Len == nil) &&
+			(x.Len == nil || exprString(x.Len) == exprString(y.Len)) &&
+			c.typeEqual(x.Elt, y.Elt)
+
+	case *goast.MapType:
+		y, ok := b.(*goast.MapType)
+		return ok && c.typeEqual(x.Key, y.Key) && c.typeEqual(x.Value, y.Value)
+
+	case *goast.ChanType:
+		y, ok := b.(*goast.ChanType)
+		return ok && x.Dir == y.Dir && c.typeEqual(x.Value, y.Value)
+
+	case *goast.IndexExpr:
+		y, ok := b.(*goast.IndexExpr)
+		return ok && c.typeEqual(x.X, y.X) && c.typeEqual(x.Index, y.Index)
+
+	case *goast.IndexListExpr:
+		y, ok := b.(*goast.IndexListExpr)
+		if !ok || !c.typeEqual(x.X, y.X) || len(x.Indices) != len(y.Indices) {
+			return false
+		}
+
+		for i := range x.Indices {
+			if !c.typeEqual(x.Indices[i], y.Indices[i]) {
+				return false
+			}
+		}
+
+		return true
+
+	// Type constraints (e.g. ~[]T | []T)
+	case *goast.UnaryExpr:
+		y, ok := b.(*goast.UnaryExpr)
+		return ok && x.Op == y.Op && c.typeEqual(x.X, y.X)
+
+	case *goast.BinaryExpr:
+		y, ok := b.(*goast.BinaryExpr)
+		return ok && x.Op == y.Op && c.typeEqual(x.X, y.X) && c.typeEqual(x.Y, y.Y)
+	}
+
+	// Qualified identifiers, structs, and interfaces are compared textually
+	return exprString(a) == exprString(b)
+}
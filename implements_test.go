@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFuncType(t *testing.T, src string) *goast.FuncType {
+	expr, err := goparser.ParseExpr(src)
+	assert.NoError(t, err)
+	return expr.(*goast.FuncType)
+}
+
+func TestImplements(t *testing.T) {
+	expr, err := goparser.ParseExpr(`interface {
+		Lookup(context.Context, *Request) (*Response, error)
+		Close() error
+		fmt.Stringer
+	}`)
+	assert.NoError(t, err)
+	iface := expr.(*goast.InterfaceType)
+
+	tests := []struct {
+		name            string
+		methods         []*Func
+		expectedMissing []string
+		expectedOK      bool
+	}{
+		{
+			name:            "NoMethods",
+			methods:         []*Func{},
+			expectedMissing: []string{"Lookup", "Close"},
+			expectedOK:      false,
+		},
+		{
+			name: "SignatureMismatch",
+			methods: []*Func{
+				{Name: "Lookup", Type: parseFuncType(t, `func(ctx context.Context, req Request) (*Response, error)`)},
+				{Name: "Close", Type: parseFuncType(t, `func() error`)},
+			},
+			expectedMissing: []string{"Lookup"},
+			expectedOK:      false,
+		},
+		{
+			name: "Implemented",
+			methods: []*Func{
+				{Name: "Lookup", Type: parseFuncType(t, `func(ctx context.Context, req *Request) (resp *Response, err error)`)},
+				{Name: "Close", Type: parseFuncType(t, `func() error`)},
+				{Name: "Open", Type: parseFuncType(t, `func() error`)},
+			},
+			expectedMissing: []string{},
+			expectedOK:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			missing, ok := Implements(iface, tc.methods)
+
+			assert.Equal(t, tc.expectedMissing, missing)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name            string
+		src             string
+		expectedMethods []string
+	}{
+		{
+			name:            "Empty",
+			src:             `interface{}`,
+			expectedMethods: []string{},
+		},
+		{
+			name:            "Constraint",
+			src:             `interface{ ~int | ~string }`,
+			expectedMethods: []string{},
+		},
+		{
+			name: "OK",
+			src: `interface {
+				fmt.Stringer
+				Lookup(ctx context.Context, req *Request) (*Response, error)
+				Close() error
+			}`,
+			expectedMethods: []string{
+				"Lookup(ctx context.Context, req *Request) ( *Response,  error)",
+				"Close() ( error)",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.src)
+			assert.NoError(t, err)
+
+			methods := []string{}
+			for _, m := range Methods(expr.(*goast.InterfaceType)) {
+				params := []string{}
+				for _, p := range m.Params {
+					params = append(params, p.Name+" "+exprString(p.Type))
+				}
+
+				results := []string{}
+				for _, p := range m.Results {
+					results = append(results, p.Name+" "+exprString(p.Type))
+				}
+
+				methods = append(methods, m.Name+"("+strings.Join(params, ", ")+") ("+strings.Join(results, ", ")+")")
+			}
+
+			assert.Equal(t, tc.expectedMethods, methods)
+		})
+	}
+}
+
+// parseSignature parses a function type, including function types with type parameters that are only allowed in declarations.
+func parseSignature(t *testing.T, src string) *goast.FuncType {
+	if !strings.HasPrefix(src, "func[") {
+		return parseFuncType(t, src)
+	}
+
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "", "package p\n\n"+strings.Replace(src, "func", "func F", 1), 0)
+	assert.NoError(t, err)
+	return file.Decls[0].(*goast.FuncDecl).Type
+}
+
+func TestSignatureEqual(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b          string
+		expectedEqual bool
+	}{
+		{"NoParams", `func()`, `func()`, true},
+		{"ParamNames", `func(ctx context.Context, id string) error`, `func(context.Context, string) error`, true},
+		{"ResultNames", `func() (resp *Response, err error)`, `func() (*Response, error)`, true},
+		{"GroupedParams", `func(a, b int) int`, `func(x int, y int) (z int)`, true},
+		{"Formatting", `func(m map[string] []int, c <-chan  int)`, `func(map[string][]int, <-chan int)`, true},
+		{"Parens", `func(a (*Request)) (error)`, `func(*Request) error`, true},
+		{"NestedFunc", `func(cb func(key string, val int) (ok bool))`, `func(func(string, int) bool)`, true},
+		{"Generic", `func(m Map[K, V], s Set[T])`, `func(Map[K, V], Set[T])`, true},
+		{"TypeParams", `func[K comparable, V any](m map[K]V) []V`, `func[K comparable, V any](map[K]V) []V`, true},
+		{"TypeParamNames", `func[T any](T)`, `func[U any](U)`, true},
+		{"TypeParamNames_Constraints", `func[S ~[]E, E comparable](s S, e E) int`, `func[T ~[]V, V comparable](T, V) int`, true},
+		{"Arity", `func(int, int)`, `func(int)`, false},
+		{"GroupedArity", `func(a, b int)`, `func(a int)`, false},
+		{"ResultArity", `func() (int, error)`, `func() int`, false},
+		{"Results", `func() error`, `func() string`, false},
+		{"Order", `func(string, int)`, `func(int, string)`, false},
+		{"Variadic", `func(args ...string)`, `func(args []string)`, false},
+		{"VariadicElem", `func(...string)`, `func(...int)`, false},
+		{"Pointer", `func(*Request)`, `func(Request)`, false},
+		{"ArrayLen", `func([2]int)`, `func([3]int)`, false},
+		{"ChanDir", `func(chan<- int)`, `func(<-chan int)`, false},
+		{"Qualified", `func(lookup.Request)`, `func(Request)`, false},
+		{"NestedFuncMismatch", `func(cb func(string) bool)`, `func(cb func(string) error)`, false},
+		{"TypeParamsMissing", `func[T any](T)`, `func(T)`, false},
+		{"TypeParamPositions", `func[K, V any](K, V)`, `func[K, V any](V, K)`, false},
+		{"TypeParamConstraints", `func[T any](T)`, `func[T comparable](T)`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := parseSignature(t, tc.a), parseSignature(t, tc.b)
+
+			assert.Equal(t, tc.expectedEqual, SignatureEqual(a, b))
+			assert.Equal(t, tc.expectedEqual, SignatureEqual(b, a))
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		assert.True(t, SignatureEqual(nil, nil))
+		assert.False(t, SignatureEqual(parseFuncType(t, `func()`), nil))
+	})
+}
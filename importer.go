@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	gotypes "go/types"
+)
+
+// importing marks a package being imported for detecting import cycles.
+var importing gotypes.Package
+
+// sourceImporter imports packages by type checking them from source.
+// Unlike the go/importer source importer, import paths are resolved relative to the directory of the importing package
+// rather than the current working directory, so the packages of the module being parsed are found wherever the parser runs.
+type sourceImporter struct {
+	fset *gotoken.FileSet
+	// packages are the imported packages keyed by their directories.
+	packages map[string]*gotypes.Package
+}
+
+func newSourceImporter(fset *gotoken.FileSet) *sourceImporter {
+	return &sourceImporter{
+		fset:     fset,
+		packages: make(map[string]*gotypes.Package),
+	}
+}
+
+// Import implements the types.Importer interface.
+func (i *sourceImporter) Import(path string) (*gotypes.Package, error) {
+	return i.ImportFrom(path, ".", 0)
+}
+
+// ImportFrom implements the types.ImporterFrom interface.
+func (i *sourceImporter) ImportFrom(path, srcDir string, _ gotypes.ImportMode) (*gotypes.Package, error) {
+	if path == "unsafe" {
+		return gotypes.Unsafe, nil
+	}
+
+	srcDir, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The go command is run in the directory of the importing package, so it resolves imports using the module of the package
+	ctxt := build.Default
+	ctxt.Dir = srcDir
+	ctxt.CgoEnabled = false
+
+	bp, err := ctxt.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if pkg, ok := i.packages[bp.Dir]; ok {
+		if pkg == &importing {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+
+	i.packages[bp.Dir] = &importing
+	defer func() {
+		if i.packages[bp.Dir] == &importing {
+			delete(i.packages, bp.Dir)
+		}
+	}()
+
+	files := make([]*goast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		file, err := goparser.ParseFile(i.fset, filepath.Join(bp.Dir, name), nil, goparser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	conf := gotypes.Config{
+		Importer:         i,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		// Continue type checking after the first error, so the exported declarations are still available
+		Error: func(error) {},
+	}
+
+	pkg, err := conf.Check(bp.ImportPath, i.fset, files, nil)
+	if err != nil {
+		return nil, fmt.Errorf("type-checking package %q failed (%s)", bp.ImportPath, err)
+	}
+
+	i.packages[bp.Dir] = pkg
+	return pkg, nil
+}
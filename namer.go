@@ -14,6 +14,30 @@ var (
 	re4 = regexp.MustCompile(`^([A-Z]+)[A-Z][0-9a-z_]`)
 )
 
+// builtins are the predeclared identifiers of the Go language specification.
+// See https://go.dev/ref/spec#Predeclared_identifiers
+var builtins = map[string]bool{
+	// Types
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "rune": true, "string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true,
+	// Constants
+	"true": true, "false": true, "iota": true,
+	// Zero value
+	"nil": true,
+	// Functions
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true, "delete": true,
+	"imag": true, "len": true, "make": true, "max": true, "min": true, "new": true, "panic": true,
+	"print": true, "println": true, "real": true, "recover": true,
+}
+
+// IsBuiltin determines whether or not a given name is a predeclared identifier (e.g. int, error, any, len).
+// Builtin types never need an import or a package qualifier.
+func IsBuiltin(name string) bool {
+	return builtins[name]
+}
+
 // IsExported determines whether or not a given name is exported.
 func IsExported(name string) bool {
 	first := name[0:1]
@@ -47,6 +71,40 @@ func InferName(expr ast.Expr) string {
 	return lastName
 }
 
+// InferPluralName infers an identifier name from a type expression using idiomatic plurals for arrays and slices.
+// For example, []string is named strings and []Entity is named Entities.
+// Nested arrays and slices are pluralized only once (e.g. [][]string is named stringsVals).
+// For any other type expression, it is the same as InferName.
+func InferPluralName(expr ast.Expr) string {
+	if v, ok := expr.(*ast.ArrayType); ok {
+		if _, ok := v.Elt.(*ast.ArrayType); ok {
+			return InferPluralName(v.Elt) + "Vals"
+		}
+		return pluralize(InferName(v.Elt))
+	}
+
+	return InferName(expr)
+}
+
+// pluralize converts a singular name to its plural form using the common English rules.
+func pluralize(name string) string {
+	switch {
+	case name == "":
+		return name
+
+	// Consonant followed by y (e.g. Entity --> Entities)
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiouAEIOU", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+
+	// Sibilant endings (e.g. Address --> Addresses, Box --> Boxes, Match --> Matches)
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+
+	return name + "s"
+}
+
 // ConvertToUnexported converts an exported identifier to an unexported one.
 func ConvertToUnexported(name string) string {
 	switch {
@@ -73,3 +131,13 @@ func ConvertToUnexported(name string) string {
 
 	panic(fmt.Sprintf("ConvertToUnexported: unexpected identifer: %s", name))
 }
+
+// QualifiedName returns the name of a type as referenced from a target package (import path).
+// The name is qualified with the package name (e.g. lookup.Request) if the type is defined in another package.
+func QualifiedName(t *Type, targetPkg string) string {
+	if t.ImportPath == targetPkg {
+		return t.Name
+	}
+
+	return t.Package.Name + "." + t.Name
+}
@@ -25,6 +25,34 @@ func TestIsExported(t *testing.T) {
 	}
 }
 
+func TestIsBuiltin(t *testing.T) {
+	tests := []struct {
+		name           string
+		expectedResult bool
+	}{
+		{"int", true},
+		{"string", true},
+		{"error", true},
+		{"any", true},
+		{"comparable", true},
+		{"iota", true},
+		{"nil", true},
+		{"len", true},
+		{"clear", true},
+		{"Request", false},
+		{"context", false},
+		{"Int", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := IsBuiltin(tc.name)
+
+			assert.Equal(t, tc.expectedResult, result)
+		})
+	}
+}
+
 func TestInferName(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -134,6 +162,92 @@ func TestInferName(t *testing.T) {
 	}
 }
 
+func TestInferPluralName(t *testing.T) {
+	tests := []struct {
+		name         string
+		expr         ast.Expr
+		expectedName string
+	}{
+		{
+			name:         "Int",
+			expr:         &ast.Ident{Name: "int"},
+			expectedName: "int",
+		},
+		{
+			name: "Strings",
+			expr: &ast.ArrayType{
+				Elt: &ast.Ident{Name: "string"},
+			},
+			expectedName: "strings",
+		},
+		{
+			name: "Entities",
+			expr: &ast.ArrayType{
+				Elt: &ast.StarExpr{
+					X: &ast.Ident{Name: "Entity"},
+				},
+			},
+			expectedName: "Entities",
+		},
+		{
+			name: "Keys",
+			expr: &ast.ArrayType{
+				Elt: &ast.Ident{Name: "key"},
+			},
+			expectedName: "keys",
+		},
+		{
+			name: "Addresses",
+			expr: &ast.ArrayType{
+				Elt: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "net"},
+					Sel: &ast.Ident{Name: "Address"},
+				},
+			},
+			expectedName: "Addresses",
+		},
+		{
+			name: "Boxes",
+			expr: &ast.ArrayType{
+				Elt: &ast.Ident{Name: "box"},
+			},
+			expectedName: "boxes",
+		},
+		{
+			name: "Matches",
+			expr: &ast.ArrayType{
+				Elt: &ast.Ident{Name: "match"},
+			},
+			expectedName: "matches",
+		},
+		{
+			name: "NestedStrings",
+			expr: &ast.ArrayType{
+				Elt: &ast.ArrayType{
+					Elt: &ast.Ident{Name: "string"},
+				},
+			},
+			expectedName: "stringsVals",
+		},
+		{
+			name: "Map",
+			expr: &ast.MapType{
+				Key:   &ast.Ident{Name: "string"},
+				Value: &ast.Ident{Name: "int"},
+			},
+			expectedName: "stringIntMap",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			name := InferPluralName(tc.expr)
+
+			assert.Equal(t, tc.expectedName, name)
+		})
+	}
+}
+
 func TestConvertToUnexported(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -173,3 +287,40 @@ func TestConvertToUnexported(t *testing.T) {
 		})
 	}
 }
+
+func TestQualifiedName(t *testing.T) {
+	typ := &Type{
+		File: File{
+			Package: Package{
+				Name:       "lookup",
+				ImportPath: "github.com/octocat/test/lookup",
+			},
+		},
+		Name: "Request",
+	}
+
+	tests := []struct {
+		name         string
+		targetPkg    string
+		expectedName string
+	}{
+		{
+			name:         "SamePackage",
+			targetPkg:    "github.com/octocat/test/lookup",
+			expectedName: "Request",
+		},
+		{
+			name:         "OtherPackage",
+			targetPkg:    "github.com/octocat/test/mock",
+			expectedName: "lookup.Request",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			name := QualifiedName(typ, tc.targetPkg)
+
+			assert.Equal(t, tc.expectedName, name)
+		})
+	}
+}
@@ -1,17 +1,31 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	goast "go/ast"
+	"go/build/constraint"
 	goparser "go/parser"
 	gotoken "go/token"
+	gotypes "go/types"
 
 	"github.com/gardenbed/charm/ui"
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 // Module contains information about a Go module.
@@ -19,13 +33,58 @@ type Module struct {
 	Name string
 }
 
+// BasePath returns the module path without the major version suffix (e.g. github.com/octocat/test for github.com/octocat/test/v2).
+func (m Module) BasePath() string {
+	prefix, _, ok := module.SplitPathVersion(m.Name)
+	if !ok {
+		return m.Name
+	}
+	return prefix
+}
+
+// MajorVersion returns the major version indicated by the module path suffix (e.g. 2 for github.com/octocat/test/v2).
+// Module paths without a major version suffix are either v0 or v1 modules, and 1 is returned for them.
+func (m Module) MajorVersion() int {
+	_, pathMajor, ok := module.SplitPathVersion(m.Name)
+	if !ok || pathMajor == "" {
+		return 1
+	}
+
+	// The suffix is either /vN or .vN (gopkg.in) with an optional -unstable suffix for gopkg.in
+	v := strings.TrimSuffix(pathMajor[2:], "-unstable")
+	major, err := strconv.Atoi(v)
+	if err != nil {
+		return 1
+	}
+
+	return major
+}
+
 // Package contains information about a parsed package.
 type Package struct {
 	Module
-	Name        string
+	// Name is the package name as declared in its files (package util).
+	// Names are not unique, since packages in different directories can have the same name.
+	Name string
+	// ImportPath is the import path of the package and, unlike Name, identifies the package.
+	// An external test package (package *_test) has the same import path as the package it tests, so ID should be used for keying packages.
 	ImportPath  string
 	BaseDir     string
 	RelativeDir string
+	// Synopsis is the first sentence of the package doc comment.
+	Synopsis string
+	// Imports are the sorted import paths imported by the files of the package.
+	// Test files and cgo files, and so the pseudo-package "C", are excluded when they are skipped.
+	Imports []string
+}
+
+// ID returns a unique identifier of the package that can be used for keying packages.
+// It is the import path of the package, suffixed with "_test" for external test packages.
+func (p *Package) ID() string {
+	if strings.HasSuffix(p.Name, "_test") {
+		return p.ImportPath + "_test"
+	}
+	return p.ImportPath
 }
 
 // File contains information about a parsed file.
@@ -33,12 +92,133 @@ type File struct {
 	Package
 	*gotoken.FileSet
 	Name string
+	// IsTest determines whether or not the file is a test file (*_test.go).
+	IsTest bool
+	// IsExternalTest determines whether or not the file is a test file in an external test package (package *_test).
+	IsExternalTest bool
+	// IsCgo determines whether or not the file uses cgo (imports "C").
+	IsCgo bool
+	// BuildTags are the build tags constraining the file and only available when parsing for all platforms.
+	// Negated tags are prefixed with "!" (e.g. !cgo). See BuildConstraint for how the tags are combined.
+	BuildTags []string
+	// BuildConstraint is the build constraint of the file implied by its name and its //go:build (or // +build) lines.
+	// It is nil if the file is not constrained and only available when parsing for all platforms.
+	BuildConstraint constraint.Expr
+	// TypesInfo is only available when type checking is enabled.
+	TypesInfo *gotypes.Info
+
+	// fsys is the file system the file was parsed from.
+	fsys fileSystem
+}
+
+// TypeOf returns the type of an expression.
+// It returns nil if type checking is not enabled or the type of the expression is unknown.
+func (f *File) TypeOf(expr goast.Expr) gotypes.Type {
+	if f.TypesInfo == nil {
+		return nil
+	}
+	return f.TypesInfo.TypeOf(expr)
+}
+
+// ZeroValue returns an expression for the zero value of a type expression in the file.
+// It uses type information when available and falls back to ZeroValue otherwise.
+func (f *File) ZeroValue(expr goast.Expr) goast.Expr {
+	if typ := f.TypeOf(expr); typ != nil {
+		return zeroValueOf(expr, typ)
+	}
+	return ZeroValue(expr)
+}
+
+// ExprString returns the Go source representation of an expression in the file.
+func (f *File) ExprString(expr goast.Expr) string {
+	return ExprString(f.FileSet, expr)
+}
+
+// symbolPath returns the import path used for qualifying the declarations in the file.
+func (f *File) symbolPath() string {
+	// Merged external test files belong to the package they test
+	if f.IsExternalTest {
+		return f.ImportPath + "_test"
+	}
+	return f.Package.ID()
+}
+
+// Source returns the source code of a node in the file as it is written.
+// The file is read again from the file system it was parsed from (the operating system file system by default).
+// An error is returned if the file is no longer available or it has been modified since it was parsed.
+func (f *File) Source(node goast.Node) (string, error) {
+	if f.FileSet == nil || !node.Pos().IsValid() || !node.End().IsValid() {
+		return "", errors.New("node position is unknown")
+	}
+
+	tf := f.FileSet.File(node.Pos())
+	if tf == nil {
+		return "", errors.New("node position is unknown")
+	}
+
+	fsys := f.fsys
+	if fsys == nil {
+		fsys = osFileSystem{}
+	}
+
+	src, err := fsys.ReadFile(tf.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if len(src) != tf.Size() {
+		return "", fmt.Errorf("%s has been modified since it was parsed", tf.Name())
+	}
+
+	return string(src[tf.Offset(node.Pos()):tf.Offset(node.End())]), nil
+}
+
+// AppendDecl appends a new declaration to a parsed file.
+// A declaration without position is positioned at the end of the file, so the comments of the file stay in place when it is written.
+// Import declarations are not appended; their imports are added to the existing import declarations instead.
+func (f *File) AppendDecl(file *goast.File, decl goast.Decl) {
+	if d, ok := decl.(*goast.GenDecl); ok && d.Tok == gotoken.IMPORT {
+		for _, spec := range d.Specs {
+			if is, ok := spec.(*goast.ImportSpec); ok {
+				importPath, _ := strconv.Unquote(is.Path.Value)
+				name := ""
+				if is.Name != nil {
+					name = is.Name.Name
+				}
+				astutil.AddNamedImport(f.FileSet, file, name, importPath)
+			}
+		}
+
+		return
+	}
+
+	end := file.FileEnd
+	if !end.IsValid() {
+		end = file.End()
+	}
+
+	if !decl.Pos().IsValid() {
+		switch d := decl.(type) {
+		case *goast.GenDecl:
+			d.TokPos = end
+		case *goast.FuncDecl:
+			d.Type.Func = end
+		}
+	}
+
+	file.Decls = append(file.Decls, decl)
 }
 
 // Type contains information about a parsed type.
 type Type struct {
 	File
 	Name string
+	// IsAlias determines whether or not the type is an alias (type A = B).
+	IsAlias bool
+	// Expr is the type expression of the type (e.g. the struct type, or the aliased type for aliases).
+	Expr goast.Expr
+	// Doc is the doc comment of the type.
+	Doc *goast.CommentGroup
 }
 
 // IsExported determines whether or not a type is exported.
@@ -46,12 +226,65 @@ func (t *Type) IsExported() bool {
 	return IsExported(t.Name)
 }
 
+// IsDeprecated determines whether or not a type is deprecated.
+func (t *Type) IsDeprecated() bool {
+	return isDeprecated(t.Doc)
+}
+
+// FullName returns the fully-qualified name of a type (e.g. github.com/octocat/test/lookup.Service).
+// Types in external test packages are qualified by the import path suffixed with "_test".
+func (t *Type) FullName() string {
+	return t.File.symbolPath() + "." + t.Name
+}
+
+// Field contains information about a struct field.
+type Field struct {
+	// Name is the name of the field, or the name of the embedded type for embedded fields.
+	Name     string
+	Type     goast.Expr
+	Tag      reflect.StructTag
+	Embedded bool
+	// Doc is the text of the comment above the field.
+	Doc string
+	// Comment is the text of the comment following the field on the same line.
+	Comment string
+}
+
+// Param contains information about a function parameter or result.
+type Param struct {
+	Name string
+	Type goast.Expr
+}
+
+// TypeParam contains information about a type parameter of a generic function or type.
+type TypeParam struct {
+	Name       string
+	Constraint goast.Expr
+}
+
+// Method contains information about an interface method.
+type Method struct {
+	Name    string
+	Params  []Param
+	Results []Param
+}
+
 // Func contains information about a parsed function.
 type Func struct {
 	File
 	Name     string
 	RecvName string
 	RecvType goast.Expr
+	Type     *goast.FuncType
+	Params   []Param
+	Results  []Param
+	// TypeParams are the type parameters of a generic function.
+	TypeParams []TypeParam
+	// HasBody determines whether or not the function has a body.
+	// Functions implemented in assembly (or linked using go:linkname) have no body.
+	HasBody bool
+	// Doc is the doc comment of the function.
+	Doc *goast.CommentGroup
 }
 
 // IsExported determines whether or not a function is exported.
@@ -59,23 +292,195 @@ func (f *Func) IsExported() bool {
 	return IsExported(f.Name)
 }
 
-// IsMethod determines if a function is a method of a struct.
+// IsMethod determines if a function is a method of a type.
+// Methods with unnamed receivers (e.g. func (T) M()) are methods too.
 func (f *Func) IsMethod() bool {
-	return f.RecvName != "" && f.RecvType != nil
+	return f.RecvType != nil
+}
+
+// ReceiverName returns the name of the receiver of a method.
+// It is empty for functions and methods with unnamed receivers.
+func (f *Func) ReceiverName() string {
+	return f.RecvName
 }
 
+// ReceiverTypeName returns the name of the receiver base type of a method (e.g. *Cache[K, V] --> Cache).
+// It is empty for functions.
+func (f *Func) ReceiverTypeName() string {
+	name, _ := receiverType(f.RecvType)
+	return name
+}
+
+// ReceiverTypeParams returns the type parameters of the receiver type of a method on a generic type
+// (e.g. *Cache[K, V] --> K, V). It is empty for functions and methods on non-generic types.
+func (f *Func) ReceiverTypeParams() []goast.Expr {
+	_, params := receiverType(f.RecvType)
+	return params
+}
+
+// ReceiverIsPointer determines whether or not a method has a pointer receiver (e.g. func (s *service) M()).
+func (f *Func) ReceiverIsPointer() bool {
+	expr := f.RecvType
+	for {
+		paren, ok := expr.(*goast.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = paren.X
+	}
+
+	_, ok := expr.(*goast.StarExpr)
+	return ok
+}
+
+// IsDeprecated determines whether or not a function is deprecated.
+func (f *Func) IsDeprecated() bool {
+	return isDeprecated(f.Doc)
+}
+
+// FullName returns the fully-qualified name of a function in the same form used by pprof and runtime stack traces.
+// Methods are qualified by their receiver types (e.g. github.com/octocat/test/lookup.(*service).Lookup),
+// and type parameters of generic receivers are elided (e.g. github.com/octocat/test/cache.(*Cache[...]).Get).
+// Declarations in external test packages are qualified by the import path suffixed with "_test".
+func (f *Func) FullName() string {
+	path := f.File.symbolPath()
+	if f.RecvType == nil {
+		return path + "." + f.Name
+	}
+
+	recv := f.ReceiverTypeName()
+	if len(f.ReceiverTypeParams()) > 0 {
+		recv += "[...]"
+	}
+
+	if f.ReceiverIsPointer() {
+		recv = "(*" + recv + ")"
+	}
+
+	return path + "." + recv + "." + f.Name
+}
+
+// IsConstructor determines if a function is a constructor for a given type.
+// A constructor is an exported function with no receiver whose first result is the type.
+func (f *Func) IsConstructor(typeName string) bool {
+	if !f.IsExported() || f.RecvType != nil || len(f.Results) == 0 {
+		return false
+	}
+
+	return InferName(f.Results[0].Type) == typeName
+}
+
+// TestKind determines the kind of a test function recognized by go test.
+type TestKind int
+
+const (
+	// NotATest is any function that is not run by go test.
+	NotATest TestKind = iota
+	// Test is a test function (func TestXxx(*testing.T)).
+	Test
+	// Benchmark is a benchmark function (func BenchmarkXxx(*testing.B)).
+	Benchmark
+	// Fuzz is a fuzz test function (func FuzzXxx(*testing.F)).
+	Fuzz
+	// Example is an example function (func ExampleXxx()).
+	Example
+)
+
+// TestKind determines the kind of a function in a test file based on its name and signature.
+// Functions outside test files, methods, generic functions, and functions with results are not tests.
+// The testing package is expected to be imported without renaming.
+func (f *Func) TestKind() TestKind {
+	if !f.IsTest || f.IsMethod() || len(f.TypeParams) > 0 || len(f.Results) > 0 {
+		return NotATest
+	}
+
+	switch {
+	case isTestName(f.Name, "Test") && f.hasTestingParam("T"):
+		return Test
+	case isTestName(f.Name, "Benchmark") && f.hasTestingParam("B"):
+		return Benchmark
+	case isTestName(f.Name, "Fuzz") && f.hasTestingParam("F"):
+		return Fuzz
+	case isTestName(f.Name, "Example") && len(f.Params) == 0:
+		return Example
+	}
+
+	return NotATest
+}
+
+// hasTestingParam determines if the function has a single parameter of type *testing.<typeName>.
+func (f *Func) hasTestingParam(typeName string) bool {
+	if len(f.Params) != 1 {
+		return false
+	}
+
+	star, ok := f.Params[0].Type.(*goast.StarExpr)
+	if !ok {
+		return false
+	}
+
+	sel, ok := star.X.(*goast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	pkg, ok := sel.X.(*goast.Ident)
+	return ok && pkg.Name == "testing" && sel.Sel.Name == typeName
+}
+
+// isTestName determines if a name is a test name with a given prefix (e.g. Test, TestXxx, or Test_xxx but not Testxxx).
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+
+	if len(name) == len(prefix) {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// ErrStopParsing can be returned from a consumer callback (FilePost) or ParseOptions.OnParseError
+// to stop parsing early without failing. No further callbacks are called, and parsing returns nil.
+// It can also be wrapped (e.g. fmt.Errorf("found: %w", ErrStopParsing)).
+var ErrStopParsing = errors.New("stop parsing")
+
 // Consumer is used for processing AST nodes.
 // This is meant to be provided by downstream packages.
 type Consumer struct {
-	Name      string
+	Name string
+	// Priority determines the order in which consumers run at each stage (lower runs first).
+	// Consumers with equal priorities run in the order they are registered.
+	Priority int
+	// Imports, if set, skips files that do not import at least one of the import paths.
+	Imports []string
+	// Directory is called with the absolute and relative paths of every visited directory,
+	// including directories without any Go file and directories excluded by the package patterns.
+	Directory func(string, string)
 	Package   func(*Package, string) bool
 	FilePre   func(*File, *goast.File) bool
+	// GenDecl is called once for each top-level general declaration (import, const, type, or var) before its specs.
+	// Returning false skips the specs of the declaration for the consumer.
+	GenDecl   func(*File, *goast.GenDecl) bool
 	Import    func(*File, *goast.ImportSpec)
 	Struct    func(*Type, *goast.StructType)
+	StructTag func(*Type, string, reflect.StructTag)
 	Interface func(*Type, *goast.InterfaceType)
 	FuncType  func(*Type, *goast.FuncType)
+	Alias     func(*Type, goast.Expr)
+	Named     func(*Type, goast.Expr)
+	// AnonStruct and AnonInterface are only called when anonymous types are included.
+	AnonStruct    func(*Type, *goast.StructType)
+	AnonInterface func(*Type, *goast.InterfaceType)
+	// FuncDecl is called with a nil body for functions without a body (see Func.HasBody).
 	FuncDecl  func(*Func, *goast.FuncType, *goast.BlockStmt)
-	FilePost  func(*File, *goast.File) error
+	Directive func(*File, string, []string, gotoken.Position)
+	// FilePost is called after all nodes of a file are processed.
+	// Returning ErrStopParsing stops parsing without an error.
+	// Generators can modify the file (e.g. using File.AppendDecl) and write it using WriteFile with File.FileSet.
+	FilePost func(*File, *goast.File) error
 }
 
 type TypeFilter struct {
@@ -90,11 +495,151 @@ type TypeFilter struct {
 // ParseOptions configure how Go source code files should be parsed.
 type ParseOptions struct {
 	SkipTestFiles bool
-	TypeFilter    TypeFilter
+	// MergeTestPackages processes the files of an external test package (package *_test)
+	// as part of the package they test in the same directory, so the package is only dispatched once.
+	// The merged files can be distinguished by File.IsExternalTest.
+	MergeTestPackages bool
+	// SkipCgoFiles skips files that use cgo (import "C").
+	SkipCgoFiles bool
+	// SkipGeneratedFiles skips processing generated files (// Code generated ... DO NOT EDIT.).
+	// Generated files are still type checked along with the rest of their packages.
+	SkipGeneratedFiles bool
+	// IncludeAnonymousTypes enables visiting anonymous struct and interface types
+	// used in type declarations (e.g. struct fields) and var and const declarations.
+	// Anonymous types are named after the declaration and the fields enclosing them (e.g. Config.Server),
+	// and they are filtered by the name of the declaration like the named types (see ExportedOnly and TypeFilter).
+	// Anonymous types in function literals are not visited.
+	IncludeAnonymousTypes bool
+	// ExportedOnly skips unexported types, functions, and methods (including methods of unexported types).
+	// This is useful for extracting the exported API surface of packages.
+	ExportedOnly bool
+	// FollowSymlinks enables traversing symbolic links to directories.
+	// By default, symbolic links are skipped.
+	FollowSymlinks bool
+	// AllowNoModule enables parsing packages that are not part of any module (e.g. GOPATH or GOROOT packages).
+	// Import paths are derived from the directory structure (the path relative to src or the directory name).
+	AllowNoModule bool
+	// ModuleResolver, if set, is called with the absolute path being parsed to determine its module name instead of looking up go.mod files.
+	// Import paths are the module name joined with the package directories relative to the path,
+	// and nested modules and replace directives are not considered.
+	ModuleResolver func(dir string) (string, error)
+	// AllPlatforms makes the build constraint of each file available through File.BuildTags and File.BuildConstraint.
+	// Files are always parsed regardless of their build constraints, so this does not change which files are parsed.
+	AllPlatforms bool
+	// ParseVendor enables parsing vendored packages under vendor directories.
+	// By default, vendor directories are skipped.
+	ParseVendor bool
+	// MaxDepth limits how deep sub-packages are parsed relative to the path when it ends with "/...".
+	// For example, 1 only parses the immediate sub-packages. 0 means unlimited.
+	MaxDepth int
+	// TypeCheck enables type checking each package using go/types.
+	// Type information will be available to consumers through File.TypeOf.
+	// Imported packages are type checked from source and resolved using the module of the importing package.
+	// Type errors are passed to OnParseError with the package directory if set; otherwise, the error aborts parsing.
+	TypeCheck bool
+	// ParserMode is OR-ed with the mode the parser needs for parsing Go source code files (e.g. goparser.Trace).
+	// All errors are always reported and comments are always parsed, so modified files can be written back with their comments.
+	ParserMode goparser.Mode
+	// ResolveObjects enables the deprecated object resolution of go/parser (goast.Ident.Obj and goast.File.Scope).
+	// Object resolution is skipped by default. It is also required for goparser.DeclarationErrors to have any effect.
+	ResolveObjects bool
+	// Cache enables reusing parsed files that have not been modified since they were last parsed.
+	// The cached files are only valid for the Compiler they were parsed with.
+	// The cached files are passed to consumers as they are and must be treated as read-only.
+	Cache ParseCache
+	// CopyCachedFiles, if set, passes copies of the cached files to consumers, so consumers can modify them
+	// (e.g. with FilePost) without the modifications leaking into the next parse.
+	// Copying a file can cost more than parsing it again, so it should only be enabled if consumers modify files.
+	CopyCachedFiles bool
+	// Progress, if set, is called when each package directory begins processing.
+	// Only directories with Go files are package directories, and their total number is discovered before parsing.
+	Progress func(current, total int, pkg string)
+	// Trace, if set, is called with the time spent in each phase of processing a package directory:
+	// "read" for reading the directory, "parse" for parsing its files, "typecheck" for type checking its packages
+	// (only when type checking is enabled), and "dispatch" for calling the consumers for its packages and files.
+	// The directory is the absolute path of the package directory. When nil, no timing is measured.
+	Trace func(event string, dir string, dur time.Duration)
+	// OnParseError, if set, is called when a file fails to parse or a package fails to type check.
+	// When a type error is skipped, the package is still processed with partial type information.
+	// Returning nil skips the file and continues parsing, while returning an error aborts parsing.
+	// Returning ErrStopParsing stops parsing without an error.
+	OnParseError func(path string, err error) error
+	// RecoverPanics enables recovering from panics in consumer callbacks.
+	// A panic is converted into a *PanicError including the consumer name and the position of the node being processed.
+	// Panics while processing a file are passed to OnParseError if set; otherwise, the error aborts parsing.
+	RecoverPanics bool
+	// IncludePatterns only includes packages whose import paths match at least one of the patterns.
+	// Patterns use the path.Match syntax (e.g. github.com/octocat/test/internal/*).
+	IncludePatterns []string
+	// ExcludePatterns excludes packages whose import paths match any of the patterns.
+	// Patterns use the path.Match syntax and take precedence over include patterns.
+	ExcludePatterns []string
+	// FilePatterns only includes files whose names match at least one of the patterns.
+	// Patterns use the path.Match syntax and are matched against the base file names (e.g. *_gen.go).
+	FilePatterns []string
+	TypeFilter   TypeFilter
+}
+
+// isGoFile determines if a directory entry is a Go source code file matching the provided options.
+func (o ParseOptions) isGoFile(e fs.DirEntry) bool {
+	return !e.IsDir() && strings.HasSuffix(e.Name(), ".go") && o.matchFile(e.Name())
+}
+
+// hasGoFiles determines if a directory has any Go source code files matching the provided options.
+func (o ParseOptions) hasGoFiles(entries []fs.DirEntry) bool {
+	for _, e := range entries {
+		if o.isGoFile(e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchFile determines if a file name is matching the provided options.
+func (o ParseOptions) matchFile(name string) bool {
+	// If no file pattern specified, it is a match
+	if len(o.FilePatterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range o.FilePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPackage determines if a package is matching the provided options.
+func (o ParseOptions) matchPackage(importPath string) bool {
+	for _, pattern := range o.ExcludePatterns {
+		if matched, _ := path.Match(pattern, importPath); matched {
+			return false
+		}
+	}
+
+	// If no include pattern specified, it is a match
+	if len(o.IncludePatterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range o.IncludePatterns {
+		if matched, _ := path.Match(pattern, importPath); matched {
+			return true
+		}
+	}
+
+	return false
 }
 
 // matchType determines if a type is matching the provided options.
 func (o ParseOptions) matchType(name *goast.Ident) bool {
+	if o.ExportedOnly && !IsExported(name.Name) {
+		return false
+	}
+
 	// If no filter specified, it is a match
 	if len(o.TypeFilter.Names) == 0 && o.TypeFilter.Regexp == nil {
 		return !o.TypeFilter.Exported || IsExported(name.Name)
@@ -107,134 +652,760 @@ func (o ParseOptions) matchType(name *goast.Ident) bool {
 		}
 	}
 
-	if o.TypeFilter.Regexp != nil && o.TypeFilter.Regexp.MatchString(name.Name) {
-		return !o.TypeFilter.Exported || IsExported(name.Name)
+	if o.TypeFilter.Regexp != nil && o.TypeFilter.Regexp.MatchString(name.Name) {
+		return !o.TypeFilter.Exported || IsExported(name.Name)
+	}
+
+	return false
+}
+
+// Parser is used for parsing Go source code files.
+type parser struct {
+	ui ui.UI
+	// mu guards the consumers and the file set.
+	// Each parse takes a snapshot of them, so they can be changed while parsing (even from consumers).
+	mu        sync.Mutex
+	consumers []*Consumer
+	// fset is shared across parses when a cache is used.
+	fset *gotoken.FileSet
+	// sources, if set, pools the buffers for reading Go source code files across parses.
+	// The parsed files never refer to their source code, so a buffer can be reused as soon as its file is parsed.
+	sources *sync.Pool
+}
+
+// newSourcePool creates a pool of buffers for reading Go source code files.
+func newSourcePool() *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			return new(bytes.Buffer)
+		},
+	}
+}
+
+// Parse processes all Go source code files in the specified path.
+// If the path ends with "/...", all subdirectories will be considered too.
+func (p *parser) Parse(path string, opts ParseOptions) error {
+	return p.parse(osFileSystem{}, []string{path}, opts)
+}
+
+// ParseFS processes all Go source code files in the specified path of a file system.
+// If the path ends with "/...", all subdirectories will be considered too.
+func (p *parser) ParseFS(fsys fs.FS, path string, opts ParseOptions) error {
+	return p.parse(ioFileSystem{fsys: fsys}, []string{path}, opts)
+}
+
+// ParseAll processes all Go source code files in the specified paths in a single run.
+// Each path can end with "/..." to consider all subdirectories too.
+// The paths share a file set and the consumers, and a package directory reachable from multiple paths is processed only once.
+func (p *parser) ParseAll(paths []string, opts ParseOptions) error {
+	return p.parse(osFileSystem{}, paths, opts)
+}
+
+// ListPackages returns the package directories in the specified path without parsing any file.
+// If the path ends with "/...", all subdirectories will be considered too.
+// A package directory is a directory with at least one Go source code file, and it is returned relative to the path.
+// Directories are traversed using the same rules as Parse, but include and exclude patterns are not applied
+// since they match import paths.
+func ListPackages(path string, opts ParseOptions) ([]string, error) {
+	fsys := osFileSystem{}
+
+	subDirs := strings.HasSuffix(path, "/...")
+	if subDirs {
+		path = strings.TrimSuffix(path, "/...")
+	}
+
+	visitOpts := visitOptions{
+		IncludeSubs:    subDirs,
+		FollowSymlinks: opts.FollowSymlinks,
+		IncludeVendor:  opts.ParseVendor,
+		MaxDepth:       opts.MaxDepth,
+	}
+
+	pkgs := []string{}
+	err := visitPackages(fsys, path, visitOpts, func(basePath, relPath string) error {
+		entries, err := fsys.ReadDir(filepath.Join(basePath, relPath))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if !opts.isGoFile(e) {
+				continue
+			}
+
+			if opts.SkipTestFiles && strings.HasSuffix(e.Name(), "_test.go") {
+				continue
+			}
+
+			pkgs = append(pkgs, relPath)
+			break
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
+
+// reset discards the file set shared across parses.
+func (p *parser) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.fset = nil
+}
+
+// addConsumer adds a new consumer after the existing ones.
+func (p *parser) addConsumer(c *Consumer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.consumers = append(p.consumers, c)
+}
+
+// removeConsumer removes all consumers with a given name.
+func (p *parser) removeConsumer(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	consumers := make([]*Consumer, 0, len(p.consumers))
+	for _, c := range p.consumers {
+		if c.Name != name {
+			consumers = append(consumers, c)
+		}
+	}
+
+	p.consumers = consumers
+}
+
+// ParsePackages parses all Go source code files in the specified path and returns them grouped by package.
+// If the path ends with "/...", all subdirectories will be considered too.
+// Packages are keyed by their import paths, and external test packages by their import paths suffixed with "_test".
+// The returned packages can be used with other tools such as go/doc or go/types.
+func ParsePackages(path string, opts ParseOptions) (map[string]*goast.Package, error) {
+	pkgs := make(map[string]*goast.Package)
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "packages",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(f *File, file *goast.File) bool {
+					key := f.ImportPath
+					if strings.HasSuffix(f.Package.Name, "_test") {
+						key += "_test"
+					}
+
+					pkg, ok := pkgs[key]
+					if !ok {
+						pkg = &goast.Package{
+							Name:  f.Package.Name,
+							Files: make(map[string]*goast.File),
+						}
+						pkgs[key] = pkg
+					}
+
+					pkg.Files[filepath.Join(f.BaseDir, f.RelativeDir, f.Name)] = file
+
+					return false
+				},
+			},
+		},
+	}
+
+	if err := p.Parse(path, opts); err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
+
+func (p *parser) parse(fsys fileSystem, paths []string, opts ParseOptions) (err error) {
+	consumers, fset := p.snapshot(opts)
+
+	if opts.RecoverPanics {
+		defer recoverPanic(&err)
+
+		for i, c := range consumers {
+			consumers[i] = recoverConsumer(c)
+		}
+	}
+
+	p.ui.Infof(ui.White, "Parsing ...")
+
+	// Keeps track of the package directories already processed from any path
+	visited := make(map[string]bool)
+
+	// Imported packages are type checked once per parse
+	var imp *sourceImporter
+	if opts.TypeCheck {
+		imp = newSourceImporter(fset)
+	}
+
+	for _, path := range paths {
+		if err := p.parsePath(fsys, fset, imp, consumers, visited, path, opts); err != nil {
+			if errors.Is(err, ErrStopParsing) {
+				p.ui.Debugf(ui.White, "Parsing stopped: %s", err)
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshot returns the consumers sorted by their priorities and the file set for a parse.
+func (p *parser) snapshot(opts ParseOptions) ([]*Consumer, *gotoken.FileSet) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	consumers := sortedConsumers(p.consumers)
+
+	// Cached files are only valid with the file set they were originally parsed with
+	if opts.Cache == nil {
+		return consumers, gotoken.NewFileSet()
+	}
+
+	if p.fset == nil {
+		p.fset = gotoken.NewFileSet()
+	}
+
+	return consumers, p.fset
+}
+
+// parsePath processes all package directories in a single path, skipping the directories already visited.
+func (p *parser) parsePath(fsys fileSystem, fset *gotoken.FileSet, imp *sourceImporter, consumers []*Consumer, visited map[string]bool, path string, opts ParseOptions) error {
+	subDirs := strings.HasSuffix(path, "/...")
+	if subDirs {
+		path = strings.TrimSuffix(path, "/...")
+	}
+
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", path)
+	}
+
+	var module string
+	var modFile *moduleFile
+	if opts.ModuleResolver != nil {
+		absPath, err := fsys.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		if module, err = opts.ModuleResolver(absPath); err != nil {
+			return err
+		}
+		p.ui.Debugf(ui.White, "Module resolved: %s", module)
+	} else if modFile, err = readModuleFile(fsys, path); err == nil {
+		module = modFile.Name()
+	} else if opts.AllowNoModule && errors.Is(err, ErrModuleNotFound) {
+		if module, err = derivedModuleName(fsys, path); err != nil {
+			return err
+		}
+		p.ui.Debugf(ui.White, "No module found, using %s", module)
+	} else {
+		return err
+	}
+
+	visitOpts := visitOptions{
+		IncludeSubs:    subDirs,
+		FollowSymlinks: opts.FollowSymlinks,
+		IncludeVendor:  opts.ParseVendor,
+		MaxDepth:       opts.MaxDepth,
+	}
+
+	// Package directories are relative to the path, but import paths are relative to the module root
+	modRelDir := "."
+	if modFile != nil {
+		absPath, err := fsys.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		if modRelDir, err = filepath.Rel(modFile.Dir, absPath); err != nil {
+			return err
+		}
+	}
+
+	nested := newNestedModules(fsys, path)
+
+	// resolvePackage resolves the module and the import path of a package directory
+	resolvePackage := func(basePath, relPath string) (Module, string, error) {
+		if vendored, ok := vendoredImportPath(relPath); ok {
+			// Vendored packages are imported using their original import paths
+			return Module{Name: module}, vendored, nil
+		}
+
+		if opts.ModuleResolver != nil {
+			return Module{Name: module}, filepath.Join(module, relPath), nil
+		}
+
+		// Packages under a nested module belong to the nested module
+		pkgModFile, pkgModule, modRelPath := modFile, module, filepath.Join(modRelDir, relPath)
+		if nestedModFile, nestedRelPath, err := nested.lookup(relPath); err != nil {
+			return Module{}, "", err
+		} else if nestedModFile != nil {
+			pkgModFile, pkgModule, modRelPath = nestedModFile, nestedModFile.Name(), nestedRelPath
+		}
+
+		if dir, err := fsys.Abs(filepath.Join(basePath, relPath)); err == nil && pkgModFile != nil {
+			// Packages under a locally replaced module are imported using the replaced module path
+			if replaced, ok := pkgModFile.replacedImportPath(dir); ok {
+				return Module{Name: pkgModule}, replaced, nil
+			}
+		}
+
+		return Module{Name: pkgModule}, filepath.Join(pkgModule, modRelPath), nil
+	}
+
+	// dirKey returns the key identifying a package directory across paths
+	dirKey := func(dir string) string {
+		if abs, err := fsys.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+
+	// Discover all matching package directories up front, so the progress total is known
+	var current, total int
+	if opts.Progress != nil {
+		err := visitPackages(fsys, path, visitOpts, func(basePath, relPath string) error {
+			if visited[dirKey(filepath.Join(basePath, relPath))] {
+				return nil
+			}
+
+			_, importPath, err := resolvePackage(basePath, relPath)
+			if err != nil {
+				return err
+			}
+
+			if !opts.matchPackage(importPath) {
+				return nil
+			}
+
+			// Directories without any Go files do not yield a package
+			entries, err := fsys.ReadDir(filepath.Join(basePath, relPath))
+			if err == nil && opts.hasGoFiles(entries) {
+				total++
+			}
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return visitPackages(fsys, path, visitOpts, func(basePath, relPath string) error {
+		absDir := filepath.Join(basePath, relPath)
+		key := dirKey(absDir)
+		if visited[key] {
+			p.ui.Debugf(ui.Cyan, "  Skipping visited directory: %s", absDir)
+			return nil
+		}
+		visited[key] = true
+
+		// DIRECTORY
+		for _, c := range consumers {
+			if c.Directory != nil {
+				c.Directory(key, relPath)
+				p.ui.Tracef(ui.Blue, "    %s.Directory", c.Name)
+			}
+		}
+
+		moduleInfo, importPath, err := resolvePackage(basePath, relPath)
+		if err != nil {
+			return err
+		}
+
+		if !opts.matchPackage(importPath) {
+			p.ui.Debugf(ui.Cyan, "  Skipping directory: %s", absDir)
+			return nil
+		}
+
+		p.ui.Debugf(ui.Cyan, "  Parsing directory: %s", absDir)
+
+		start := opts.now()
+		entries, err := fsys.ReadDir(absDir)
+		if err != nil {
+			return fmt.Errorf("Error on reading directory %s: %s", absDir, err)
+		}
+		opts.trace("read", key, start)
+
+		if opts.Progress != nil && opts.hasGoFiles(entries) {
+			current++
+			opts.Progress(current, total, importPath)
+		}
+
+		// Parse all Go files in the current directory and build a map of package names to parsed files.
+		start = opts.now()
+		files := make(map[string]map[string]*goast.File)
+		for _, e := range entries {
+			if !opts.isGoFile(e) {
+				continue
+			}
+
+			filename := filepath.Join(absDir, e.Name())
+
+			file, err := p.parseFile(fsys, fset, filename, e, opts)
+			if err != nil {
+				if opts.OnParseError == nil {
+					return err
+				}
+
+				if err := opts.OnParseError(filename, err); err != nil {
+					return err
+				}
+
+				p.ui.Debugf(ui.Yellow, "    Skipping file: %s", filename)
+				continue
+			}
+
+			pkgName := file.Name.Name
+			if _, ok := files[pkgName]; !ok {
+				files[pkgName] = make(map[string]*goast.File)
+			}
+			files[pkgName][filename] = file
+		}
+
+		opts.trace("parse", key, start)
+
+		if opts.MergeTestPackages {
+			mergeTestPackages(files)
+		}
+
+		// Time spent on type checking is excluded from dispatching
+		start = opts.now()
+		var typeCheckDur time.Duration
+
+		// Visit all parsed Go files in each package
+		for _, pkgName := range sortedKeys(files) {
+			pkgFiles := files[pkgName]
+			p.ui.Debugf(ui.Magenta, "    Package: %s", pkgName)
+
+			pkgInfo := Package{
+				Module:      moduleInfo,
+				Name:        pkgName,
+				ImportPath:  importPath,
+				BaseDir:     basePath,
+				RelativeDir: relPath,
+				Synopsis:    packageSynopsis(pkgFiles),
+				Imports:     packageImports(pkgFiles, !opts.SkipTestFiles, !opts.SkipCgoFiles),
+			}
+
+			// Keeps track of interested consumers in the files in the current package
+			fileConsumers := make([]*Consumer, 0)
+
+			// PACKAGE
+			for _, c := range consumers {
+				if c.Package != nil {
+					cont := c.Package(&pkgInfo, pkgName)
+					if cont {
+						fileConsumers = append(fileConsumers, c)
+					}
+					p.ui.Tracef(ui.Blue, "      %s.Package: %t", c.Name, cont)
+				}
+			}
+
+			// Proceed to the next package if no consumer
+			if len(fileConsumers) == 0 {
+				continue
+			}
+
+			if opts.SkipTestFiles {
+				for filename := range pkgFiles {
+					if strings.HasSuffix(filename, "_test.go") {
+						delete(pkgFiles, filename)
+					}
+				}
+			}
+
+			if opts.SkipCgoFiles {
+				for filename, file := range pkgFiles {
+					if isCgo(file) {
+						delete(pkgFiles, filename)
+					}
+				}
+			}
+
+			var info, xtestInfo *gotypes.Info
+			if opts.TypeCheck {
+				p.ui.Debugf(ui.Magenta, "    Type checking package: %s", pkgName)
+				typeCheckStart := opts.now()
+
+				// Merged external test files are type checked as a separate package
+				typeFiles, xtestFiles := splitPackageFiles(pkgFiles, pkgName)
+				info, err = typeCheck(fset, imp, importPath, typeFiles)
+				if err := p.onTypeError(absDir, err, opts); err != nil {
+					return err
+				}
+
+				if len(xtestFiles) > 0 {
+					xtestInfo, err = typeCheck(fset, imp, importPath+"_test", xtestFiles)
+					if err := p.onTypeError(absDir, err, opts); err != nil {
+						return err
+					}
+				}
+
+				if opts.Trace != nil {
+					typeCheckDur += time.Since(typeCheckStart)
+				}
+			}
+
+			for _, filename := range sortedKeys(pkgFiles) {
+				file := pkgFiles[filename]
+				if opts.SkipGeneratedFiles && goast.IsGenerated(file) {
+					p.ui.Debugf(ui.Green, "      Skipping generated file: %s", filename)
+					continue
+				}
+
+				fileTypesInfo := info
+				if file.Name.Name != pkgName {
+					fileTypesInfo = xtestInfo
+				}
+
+				if err := p.processFile(fsys, pkgInfo, fset, fileTypesInfo, filename, file, fileConsumers, opts); err != nil {
+					// Recovered consumer panics follow the same policy as parse errors
+					var pe *PanicError
+					if !errors.As(err, &pe) || opts.OnParseError == nil {
+						return err
+					}
+
+					if err := opts.OnParseError(filename, err); err != nil {
+						return err
+					}
+
+					p.ui.Debugf(ui.Yellow, "      Skipping the rest of file: %s", filename)
+				}
+			}
+		}
+
+		if opts.Trace != nil {
+			if opts.TypeCheck {
+				opts.Trace("typecheck", key, typeCheckDur)
+			}
+			opts.Trace("dispatch", key, time.Since(start)-typeCheckDur)
+		}
+
+		return nil
+	})
+}
+
+// sortedConsumers returns a copy of consumers stably sorted by their priorities.
+func sortedConsumers(consumers []*Consumer) []*Consumer {
+	sorted := make([]*Consumer, len(consumers))
+	copy(sorted, consumers)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return sorted
+}
+
+// processAnonymousTypes visits the anonymous struct and interface types nested in the nodes of a declaration.
+// Anonymous types are named after the declaration and the fields enclosing them (e.g. Config.Server).
+// If a node itself is a struct or interface type, it is only visited if visitRoot is true.
+// Function literals are not descended into.
+func (p *parser) processAnonymousTypes(fileInfo File, name string, nodes []goast.Node, visitRoot bool, declConsumers []*Consumer) {
+	// Keeps track of the names already used, so multiple anonymous types enclosed by the same field have unique names
+	names := make(map[string]int)
+	uniqueName := func(name string) string {
+		names[name]++
+		if n := names[name]; n > 1 {
+			return fmt.Sprintf("%s%d", name, n)
+		}
+		return name
+	}
+
+	var walk func(name string, node goast.Node, visitRoot bool)
+	walk = func(name string, node goast.Node, visitRoot bool) {
+		goast.Inspect(node, func(n goast.Node) bool {
+			switch v := n.(type) {
+			case *goast.FuncLit:
+				return false
+
+			// Named fields, parameters, and methods
+			case *goast.Field:
+				if len(v.Names) > 0 {
+					walk(name+"."+v.Names[0].Name, v.Type, true)
+					return false
+				}
+
+			// ANONYMOUS STRUCT
+			case *goast.StructType:
+				if n == node && !visitRoot {
+					return true
+				}
+
+				typeInfo := Type{
+					File: fileInfo,
+					Name: uniqueName(name),
+					Expr: v,
+				}
+
+				p.ui.Debugf(ui.Yellow, "          AnonStruct: %s", typeInfo.Name)
+				for _, c := range declConsumers {
+					if c.AnonStruct != nil {
+						c.AnonStruct(&typeInfo, v)
+						p.ui.Tracef(ui.Blue, "            %s.AnonStruct", c.Name)
+					}
+				}
+
+			// ANONYMOUS INTERFACE
+			case *goast.InterfaceType:
+				if n == node && !visitRoot {
+					return true
+				}
+
+				typeInfo := Type{
+					File: fileInfo,
+					Name: uniqueName(name),
+					Expr: v,
+				}
+
+				p.ui.Debugf(ui.Yellow, "          AnonInterface: %s", typeInfo.Name)
+				for _, c := range declConsumers {
+					if c.AnonInterface != nil {
+						c.AnonInterface(&typeInfo, v)
+						p.ui.Tracef(ui.Blue, "            %s.AnonInterface", c.Name)
+					}
+				}
+			}
+
+			return true
+		})
 	}
 
-	return false
-}
-
-// Parser is used for parsing Go source code files.
-type parser struct {
-	ui        ui.UI
-	consumers []*Consumer
+	for _, node := range nodes {
+		walk(name, node, visitRoot)
+	}
 }
 
-// Parse processes all Go source code files in the specified path.
-// If the path ends with "/...", all subdirectories will be considered too.
-func (p *parser) Parse(path string, opts ParseOptions) error {
-	subDirs := strings.HasSuffix(path, "/...")
-	if subDirs {
-		path = strings.TrimSuffix(path, "/...")
+// now returns the current time only if tracing is enabled, so timing is not measured otherwise.
+func (o ParseOptions) now() time.Time {
+	if o.Trace == nil {
+		return time.Time{}
 	}
+	return time.Now()
+}
 
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
+// trace reports the time spent in a phase of processing a package directory since a start time.
+func (o ParseOptions) trace(event, dir string, start time.Time) {
+	if o.Trace != nil {
+		o.Trace(event, dir, time.Since(start))
 	}
+}
 
-	if !info.IsDir() {
-		return fmt.Errorf("%q is not a directory", path)
+// parseMode returns the mode for parsing Go source code files.
+// Comments are always parsed, so doc comments, directives, build constraints, and generated file markers are available.
+func (o ParseOptions) parseMode() goparser.Mode {
+	mode := o.ParserMode | goparser.AllErrors | goparser.ParseComments
+	if !o.ResolveObjects {
+		mode |= goparser.SkipObjectResolution
 	}
 
-	p.ui.Infof(ui.White, "Parsing ...")
+	return mode
+}
 
-	fset := gotoken.NewFileSet()
+// readSource reads a Go source code file into a pooled buffer if pooling is enabled.
+// The returned function releases the buffer back to the pool, so the source code must not be used afterwards.
+func (p *parser) readSource(fsys fileSystem, filename string) ([]byte, func(), error) {
+	if p.sources == nil {
+		src, err := fsys.ReadFile(filename)
+		return src, func() {}, err
+	}
 
-	module, err := getModuleName(path)
+	f, err := fsys.Open(filename)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	defer f.Close()
 
-	moduleInfo := Module{
-		Name: module,
-	}
+	buf := p.sources.Get().(*bytes.Buffer)
+	buf.Reset()
 
-	return visitPackages(subDirs, path, func(basePath, relPath string) error {
-		absDir := filepath.Join(basePath, relPath)
-		importPath := filepath.Join(module, relPath)
+	if _, err := buf.ReadFrom(f); err != nil {
+		p.sources.Put(buf)
+		return nil, nil, err
+	}
 
-		p.ui.Debugf(ui.Cyan, "  Parsing directory: %s", absDir)
+	return buf.Bytes(), func() { p.sources.Put(buf) }, nil
+}
 
-		entries, err := os.ReadDir(absDir)
+// parseFile parses a Go source code file or reuses a cached one if it has not been modified.
+func (p *parser) parseFile(fsys fileSystem, fset *gotoken.FileSet, filename string, entry fs.DirEntry, opts ParseOptions) (*goast.File, error) {
+	var modTime time.Time
+	if opts.Cache != nil {
+		info, err := entry.Info()
 		if err != nil {
-			return fmt.Errorf("Error on reading directory %s: %s", absDir, err)
+			return nil, err
 		}
 
-		// Parse all Go files in the current directory and build a map of package names to parsed files.
-		files := make(map[string]map[string]*goast.File)
-		for _, e := range entries {
-			if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
-				continue
-			}
-
-			filename := filepath.Join(absDir, e.Name())
-
-			file, err := goparser.ParseFile(fset, filename, nil, goparser.SkipObjectResolution|goparser.AllErrors)
-			if err != nil {
-				return err
-			}
-
-			pkgName := file.Name.Name
-			if _, ok := files[pkgName]; !ok {
-				files[pkgName] = make(map[string]*goast.File)
+		modTime = info.ModTime()
+		if file, ok := opts.Cache.Get(filename, modTime); ok {
+			p.ui.Tracef(ui.Blue, "      Cached: %s", filename)
+			if opts.CopyCachedFiles {
+				file = copyFile(file)
 			}
-			files[pkgName][filename] = file
+			return file, nil
 		}
+	}
 
-		// Visit all parsed Go files in each package
-		for pkgName, pkgFiles := range files {
-			p.ui.Debugf(ui.Magenta, "    Package: %s", pkgName)
-
-			pkgInfo := Package{
-				Module:      moduleInfo,
-				Name:        pkgName,
-				ImportPath:  importPath,
-				BaseDir:     basePath,
-				RelativeDir: relPath,
-			}
-
-			// Keeps track of interested consumers in the files in the current package
-			fileConsumers := make([]*Consumer, 0)
-
-			// PACKAGE
-			for _, c := range p.consumers {
-				if c.Package != nil {
-					cont := c.Package(&pkgInfo, pkgName)
-					if cont {
-						fileConsumers = append(fileConsumers, c)
-					}
-					p.ui.Tracef(ui.Blue, "      %s.Package: %t", c.Name, cont)
-				}
-			}
-
-			// Proceed to the next package if no consumer
-			if len(fileConsumers) == 0 {
-				continue
-			}
+	src, release, err := p.readSource(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
 
-			for filename, file := range pkgFiles {
-				if opts.SkipTestFiles && strings.HasSuffix(filename, "_test.go") {
-					continue
-				}
+	file, err := goparser.ParseFile(fset, filename, src, opts.parseMode())
+	release()
+	if err != nil {
+		return nil, err
+	}
 
-				if err := p.processFile(pkgInfo, fset, filename, file, fileConsumers, opts); err != nil {
-					return err
-				}
-			}
+	if opts.Cache != nil {
+		cached := file
+		if opts.CopyCachedFiles {
+			cached = copyFile(file)
 		}
+		opts.Cache.Put(filename, modTime, cached)
+	}
 
-		return nil
-	})
+	return file, nil
 }
 
-func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName string, file *goast.File, fileConsumers []*Consumer, opts ParseOptions) error {
+func (p *parser) processFile(fsys fileSystem, pkgInfo Package, fset *gotoken.FileSet, info *gotypes.Info, fileName string, file *goast.File, fileConsumers []*Consumer, opts ParseOptions) (err error) {
+	if opts.RecoverPanics {
+		defer recoverPanic(&err)
+	}
+
 	p.ui.Debugf(ui.Green, "      File: %s", fileName)
 
 	fileInfo := File{
-		Package: pkgInfo,
-		FileSet: fset,
-		Name:    filepath.Base(fileName),
+		Package:        pkgInfo,
+		FileSet:        fset,
+		Name:           filepath.Base(fileName),
+		IsTest:         strings.HasSuffix(fileName, "_test.go"),
+		IsExternalTest: strings.HasSuffix(fileName, "_test.go") && strings.HasSuffix(file.Name.Name, "_test"),
+		IsCgo:          isCgo(file),
+		TypesInfo:      info,
+		fsys:           fsys,
+	}
+
+	if opts.AllPlatforms {
+		fileInfo.BuildConstraint = buildConstraint(fileName, file)
+		fileInfo.BuildTags = buildTags(fileInfo.BuildConstraint)
 	}
 
 	// Keeps track of interested consumers in the declarations in the current file
@@ -242,6 +1413,12 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 
 	// FILE (pre)
 	for _, c := range fileConsumers {
+		// Skip the file if it does not import any of the paths the consumer is interested in
+		if len(c.Imports) > 0 && !importsAny(file, c.Imports) {
+			p.ui.Tracef(ui.Blue, "        %s.Imports: false", c.Name)
+			continue
+		}
+
 		if c.FilePre != nil {
 			cont := c.FilePre(&fileInfo, file)
 			if cont {
@@ -256,12 +1433,54 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 		return nil
 	}
 
+	// DIRECTIVE
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			directive, args, ok := parseDirective(comment.Text)
+			if !ok {
+				continue
+			}
+
+			p.ui.Debugf(ui.Yellow, "          Directive: %s", directive)
+			for _, c := range declConsumers {
+				if c.Directive != nil {
+					c.Directive(&fileInfo, directive, args, fset.Position(comment.Slash))
+					p.ui.Tracef(ui.Blue, "            %s.Directive", c.Name)
+				}
+			}
+		}
+	}
+
+	// Keeps track of the current declaration for its doc comment and its interested consumers
+	var genDecl *goast.GenDecl
+	specConsumers := declConsumers
+
 	goast.Inspect(file, func(n goast.Node) bool {
 		switch v := n.(type) {
+		case *goast.GenDecl:
+			genDecl = v
+
+			// GEN DECL
+			specConsumers = make([]*Consumer, 0, len(declConsumers))
+			for _, c := range declConsumers {
+				if c.GenDecl == nil {
+					specConsumers = append(specConsumers, c)
+					continue
+				}
+
+				cont := c.GenDecl(&fileInfo, v)
+				if cont {
+					specConsumers = append(specConsumers, c)
+				}
+				p.ui.Tracef(ui.Blue, "            %s.GenDecl: %t", c.Name, cont)
+			}
+
+			return len(specConsumers) > 0
+
 		// IMPORT
 		case *goast.ImportSpec:
 			p.ui.Debugf(ui.Yellow, "          ImportSpec: %s", v.Path.Value)
-			for _, c := range declConsumers {
+			for _, c := range specConsumers {
 				if c.Import != nil {
 					c.Import(&fileInfo, v)
 					p.ui.Tracef(ui.Blue, "            %s.Import", c.Name)
@@ -272,28 +1491,48 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 		// Handle Types
 		case *goast.TypeSpec:
 			typeInfo := Type{
-				File: fileInfo,
-				Name: v.Name.Name,
+				File:    fileInfo,
+				Name:    v.Name.Name,
+				IsAlias: v.Assign.IsValid(),
+				Expr:    v.Type,
+				Doc:     v.Doc,
+			}
+
+			// The doc comment of a non-grouped declaration is attached to the declaration
+			if typeInfo.Doc == nil && genDecl != nil && !genDecl.Lparen.IsValid() {
+				typeInfo.Doc = genDecl.Doc
+			}
+
+			if opts.IncludeAnonymousTypes && opts.matchType(v.Name) {
+				p.processAnonymousTypes(fileInfo, v.Name.Name, []goast.Node{v.Type}, false, specConsumers)
 			}
 
 			switch w := v.Type.(type) {
 			// STRUCT
 			case *goast.StructType:
 				p.ui.Debugf(ui.Yellow, "          StructType: %s", v.Name.Name)
-				for _, c := range declConsumers {
+				for _, c := range specConsumers {
 					if c.Struct != nil {
 						if opts.matchType(v.Name) {
 							c.Struct(&typeInfo, w)
 							p.ui.Tracef(ui.Blue, "            %s.Struct", c.Name)
 						}
 					}
+					if c.StructTag != nil {
+						if opts.matchType(v.Name) {
+							visitStructTags(w, func(fieldName string, tag reflect.StructTag) {
+								c.StructTag(&typeInfo, fieldName, tag)
+								p.ui.Tracef(ui.Blue, "            %s.StructTag: %s", c.Name, fieldName)
+							})
+						}
+					}
 				}
 				return false
 
 			// INTERFACE
 			case *goast.InterfaceType:
 				p.ui.Debugf(ui.Yellow, "          InterfaceType: %s", v.Name.Name)
-				for _, c := range declConsumers {
+				for _, c := range specConsumers {
 					if c.Interface != nil {
 						if opts.matchType(v.Name) {
 							c.Interface(&typeInfo, w)
@@ -306,7 +1545,7 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 			// FUNCTION (type)
 			case *goast.FuncType:
 				p.ui.Debugf(ui.Yellow, "          FuncType: %s", v.Name.Name)
-				for _, c := range declConsumers {
+				for _, c := range specConsumers {
 					if c.FuncType != nil {
 						if opts.matchType(v.Name) {
 							c.FuncType(&typeInfo, w)
@@ -315,15 +1554,64 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 					}
 				}
 				return false
+
+			// ALIAS
+			default:
+				if typeInfo.IsAlias {
+					p.ui.Debugf(ui.Yellow, "          Alias: %s", v.Name.Name)
+					for _, c := range specConsumers {
+						if c.Alias != nil {
+							if opts.matchType(v.Name) {
+								c.Alias(&typeInfo, v.Type)
+								p.ui.Tracef(ui.Blue, "            %s.Alias", c.Name)
+							}
+						}
+					}
+					return false
+				}
+
+				// NAMED (any other defined type)
+				p.ui.Debugf(ui.Yellow, "          Named: %s", v.Name.Name)
+				for _, c := range specConsumers {
+					if c.Named != nil {
+						if opts.matchType(v.Name) {
+							c.Named(&typeInfo, v.Type)
+							p.ui.Tracef(ui.Blue, "            %s.Named", c.Name)
+						}
+					}
+				}
+				return false
 			}
 
+		// Handle Values
+		case *goast.ValueSpec:
+			// Anonymous types in var and const declarations are named after (and filtered by) the first declared name
+			if opts.IncludeAnonymousTypes && opts.matchType(v.Names[0]) {
+				nodes := make([]goast.Node, 0, len(v.Values)+1)
+				if v.Type != nil {
+					nodes = append(nodes, v.Type)
+				}
+				for _, value := range v.Values {
+					nodes = append(nodes, value)
+				}
+
+				p.processAnonymousTypes(fileInfo, v.Names[0].Name, nodes, true, specConsumers)
+			}
+			return false
+
 		// FUNCTION (declaration)
 		case *goast.FuncDecl:
 			p.ui.Debugf(ui.Yellow, "          FuncDecl: %s", v.Name.Name)
 
 			funcInfo := Func{
-				File: fileInfo,
-				Name: v.Name.Name,
+				File:       fileInfo,
+				Name:       v.Name.Name,
+				Type:       v.Type,
+				Params:     fieldParams(v.Type.Params),
+				Results:    fieldParams(v.Type.Results),
+				TypeParams: TypeParamsOf(v.Type),
+				HasBody:    v.Body != nil,
+				Doc:        v.Doc,
 			}
 
 			if v.Recv != nil && len(v.Recv.List) == 1 {
@@ -333,6 +1621,10 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 				funcInfo.RecvType = v.Recv.List[0].Type
 			}
 
+			if opts.ExportedOnly && !isExportedFunc(&funcInfo) {
+				return false
+			}
+
 			for _, c := range declConsumers {
 				if c.FuncDecl != nil {
 					c.FuncDecl(&funcInfo, v.Type, v.Body)
@@ -351,7 +1643,7 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 		if c.FilePost != nil {
 			err := c.FilePost(&fileInfo, file)
 			if err != nil {
-				return err
+				return fmt.Errorf("consumer %q failed on %s: %w", c.Name, fileInfo.Name, err)
 			}
 			p.ui.Tracef(ui.Blue, "        %s.FilePost", c.Name)
 		}
@@ -359,3 +1651,266 @@ func (p *parser) processFile(pkgInfo Package, fset *gotoken.FileSet, fileName st
 
 	return nil
 }
+
+// isDeprecated determines if a doc comment has a paragraph starting with "Deprecated: ".
+func isDeprecated(doc *goast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated: ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// packageSynopsis returns the synopsis of the first package doc comment found in the non-test files of a package.
+func packageSynopsis(pkgFiles map[string]*goast.File) string {
+	for _, filename := range sortedKeys(pkgFiles) {
+		if file := pkgFiles[filename]; file.Doc != nil && !strings.HasSuffix(filename, "_test.go") {
+			return Synopsis(file.Doc)
+		}
+	}
+
+	return ""
+}
+
+// packageImports returns the sorted and deduplicated import paths of the files of a package.
+// The imports of test files and cgo files, including the pseudo-package "C", are only included if requested.
+func packageImports(pkgFiles map[string]*goast.File, includeTests, includeCgo bool) []string {
+	paths := make(map[string]bool)
+	for filename, file := range pkgFiles {
+		if !includeTests && strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+
+		if !includeCgo && isCgo(file) {
+			continue
+		}
+
+		for _, spec := range file.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+				paths[importPath] = true
+			}
+		}
+	}
+
+	return sortedKeys(paths)
+}
+
+// importsAny determines whether or not a file imports at least one of the given import paths.
+func importsAny(file *goast.File, paths []string) bool {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		for _, p := range paths {
+			if importPath == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isExportedFunc determines whether or not a function is part of the exported API (exported methods of exported types).
+func isExportedFunc(f *Func) bool {
+	if !f.IsExported() {
+		return false
+	}
+
+	if f.RecvType != nil {
+		name := f.ReceiverTypeName()
+		return name != "" && IsExported(name)
+	}
+
+	return true
+}
+
+// receiverType returns the name and the type parameters of a receiver type (e.g. *Cache[K, V] --> Cache, [K, V]).
+func receiverType(expr goast.Expr) (string, []goast.Expr) {
+	switch v := expr.(type) {
+	case *goast.Ident:
+		return v.Name, []goast.Expr{}
+	case *goast.StarExpr:
+		return receiverType(v.X)
+	case *goast.ParenExpr:
+		return receiverType(v.X)
+	case *goast.IndexExpr:
+		name, _ := receiverType(v.X)
+		return name, []goast.Expr{v.Index}
+	case *goast.IndexListExpr:
+		name, _ := receiverType(v.X)
+		return name, v.Indices
+	}
+
+	return "", []goast.Expr{}
+}
+
+// isCgo determines whether or not a file uses cgo.
+func isCgo(file *goast.File) bool {
+	return importsAny(file, []string{"C"})
+}
+
+// parseDirective parses a //go: directive comment (e.g. //go:generate go run gen.go).
+// The directive name includes the go: prefix and arguments are separated by white spaces.
+func parseDirective(text string) (string, []string, bool) {
+	if !strings.HasPrefix(text, "//go:") {
+		return "", nil, false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(text, "//"))
+	if len(fields) == 0 || fields[0] == "go:" {
+		return "", nil, false
+	}
+
+	return fields[0], fields[1:], true
+}
+
+// fieldParams converts a list of function parameters or results to a flat list with one entry per name.
+func fieldParams(fields *goast.FieldList) []Param {
+	params := []Param{}
+	if fields == nil {
+		return params
+	}
+
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			params = append(params, Param{Type: field.Type})
+			continue
+		}
+
+		for _, name := range field.Names {
+			params = append(params, Param{
+				Name: name.Name,
+				Type: field.Type,
+			})
+		}
+	}
+
+	return params
+}
+
+// visitStructTags calls a function for every tagged field of a struct type.
+// Embedded fields are named after their types.
+func visitStructTags(st *goast.StructType, visit func(string, reflect.StructTag)) {
+	if st.Fields == nil {
+		return
+	}
+
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+
+		if len(field.Names) == 0 {
+			visit(InferName(field.Type), reflect.StructTag(tag))
+			continue
+		}
+
+		for _, name := range field.Names {
+			visit(name.Name, reflect.StructTag(tag))
+		}
+	}
+}
+
+// mergeTestPackages merges the files of external test packages into the packages they test.
+// An external test package without the package it tests is kept as is.
+func mergeTestPackages(files map[string]map[string]*goast.File) {
+	for pkgName, pkgFiles := range files {
+		baseFiles, ok := files[strings.TrimSuffix(pkgName, "_test")]
+		if !strings.HasSuffix(pkgName, "_test") || !ok {
+			continue
+		}
+
+		for filename, file := range pkgFiles {
+			baseFiles[filename] = file
+		}
+		delete(files, pkgName)
+	}
+}
+
+// splitPackageFiles splits the files of a package into the files declaring the package and the merged external test files.
+func splitPackageFiles(pkgFiles map[string]*goast.File, pkgName string) (map[string]*goast.File, map[string]*goast.File) {
+	files := make(map[string]*goast.File)
+	xtestFiles := make(map[string]*goast.File)
+
+	for filename, file := range pkgFiles {
+		if file.Name.Name == pkgName {
+			files[filename] = file
+		} else {
+			xtestFiles[filename] = file
+		}
+	}
+
+	return files, xtestFiles
+}
+
+// typeCheck runs the type checker on all files of a package.
+func typeCheck(fset *gotoken.FileSet, imp gotypes.ImporterFrom, importPath string, pkgFiles map[string]*goast.File) (*gotypes.Info, error) {
+	files := make([]*goast.File, 0, len(pkgFiles))
+	for _, filename := range sortedKeys(pkgFiles) {
+		files = append(files, pkgFiles[filename])
+	}
+
+	info := &gotypes.Info{
+		Types:      make(map[goast.Expr]gotypes.TypeAndValue),
+		Defs:       make(map[*goast.Ident]gotypes.Object),
+		Uses:       make(map[*goast.Ident]gotypes.Object),
+		Implicits:  make(map[goast.Node]gotypes.Object),
+		Selections: make(map[*goast.SelectorExpr]*gotypes.Selection),
+		Scopes:     make(map[goast.Node]*gotypes.Scope),
+	}
+
+	conf := gotypes.Config{
+		Importer: imp,
+		// Continue type checking after the first error, so the type information is as complete as possible
+		Error: func(error) {},
+	}
+
+	// The type information is partial if there is an error
+	_, err := conf.Check(importPath, fset, files, info)
+
+	return info, err
+}
+
+// onTypeError handles an error from type checking a package in a directory.
+// Type errors follow the same policy as parse errors.
+func (p *parser) onTypeError(dir string, err error, opts ParseOptions) error {
+	if err == nil {
+		return nil
+	}
+
+	if opts.OnParseError == nil {
+		return err
+	}
+
+	if err := opts.OnParseError(dir, err); err != nil {
+		return err
+	}
+
+	p.ui.Debugf(ui.Yellow, "      Continuing with partial type information: %s", dir)
+	return nil
+}
+
+// sortedKeys returns the keys of a map in sorted order, so maps can be iterated deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -1,16 +1,115 @@
 package parser
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	gotypes "go/types"
 
 	"github.com/gardenbed/charm/ui"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestPackage_ID(t *testing.T) {
+	t.Run("ExternalTest", func(t *testing.T) {
+		pkg := &Package{Name: "lookup_test", ImportPath: "github.com/octocat/test/lookup"}
+		assert.Equal(t, "github.com/octocat/test/lookup_test", pkg.ID())
+	})
+
+	t.Run("SameNames", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"go.mod":                {Data: []byte("module github.com/octocat/test\n")},
+			"client/util/util.go":   {Data: []byte("package util\n")},
+			"server/util/util.go":   {Data: []byte("package util\n")},
+			"server/util/x_test.go": {Data: []byte("package util_test\n")},
+		}
+
+		ids := []string{}
+		p := &parser{
+			ui: ui.NewNop(),
+			consumers: []*Consumer{
+				{
+					Name: "tester",
+					Package: func(pkg *Package, name string) bool {
+						assert.Equal(t, "util", strings.TrimSuffix(name, "_test"))
+						ids = append(ids, pkg.ID())
+						return false
+					},
+				},
+			},
+		}
+
+		err := p.ParseFS(fsys, "./...", ParseOptions{})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"github.com/octocat/test/client/util",
+			"github.com/octocat/test/server/util",
+			"github.com/octocat/test/server/util_test",
+		}, ids)
+	})
+}
+
+func TestModule_SemanticImportVersioning(t *testing.T) {
+	tests := []struct {
+		name             string
+		module           Module
+		expectedBasePath string
+		expectedMajor    int
+	}{
+		{
+			name:             "NoSuffix",
+			module:           Module{Name: "github.com/octocat/test"},
+			expectedBasePath: "github.com/octocat/test",
+			expectedMajor:    1,
+		},
+		{
+			name:             "MajorVersion",
+			module:           Module{Name: "github.com/octocat/test/v2"},
+			expectedBasePath: "github.com/octocat/test",
+			expectedMajor:    2,
+		},
+		{
+			name:             "GopkgIn",
+			module:           Module{Name: "gopkg.in/yaml.v3"},
+			expectedBasePath: "gopkg.in/yaml",
+			expectedMajor:    3,
+		},
+		{
+			name:             "GopkgInUnstable",
+			module:           Module{Name: "gopkg.in/check.v1-unstable"},
+			expectedBasePath: "gopkg.in/check",
+			expectedMajor:    1,
+		},
+		{
+			name:             "InvalidSuffix",
+			module:           Module{Name: "github.com/octocat/test/v1"},
+			expectedBasePath: "github.com/octocat/test/v1",
+			expectedMajor:    1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedBasePath, tc.module.BasePath())
+			assert.Equal(t, tc.expectedMajor, tc.module.MajorVersion())
+		})
+	}
+}
+
 func TestTypeInfo_IsExported(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -73,6 +172,100 @@ func TestFuncInfo_IsExported(t *testing.T) {
 	}
 }
 
+func TestTypeInfo_IsDeprecated(t *testing.T) {
+	tests := []struct {
+		name                 string
+		info                 *Type
+		expectedIsDeprecated bool
+	}{
+		{
+			name:                 "NoDoc",
+			info:                 &Type{Name: "Request"},
+			expectedIsDeprecated: false,
+		},
+		{
+			name: "NotDeprecated",
+			info: &Type{
+				Name: "Request",
+				Doc: &goast.CommentGroup{
+					List: []*goast.Comment{
+						{Text: "// Request is the lookup request."},
+					},
+				},
+			},
+			expectedIsDeprecated: false,
+		},
+		{
+			name: "Deprecated",
+			info: &Type{
+				Name: "Request",
+				Doc: &goast.CommentGroup{
+					List: []*goast.Comment{
+						{Text: "// Request is the lookup request."},
+						{Text: "//"},
+						{Text: "// Deprecated: use Query instead."},
+					},
+				},
+			},
+			expectedIsDeprecated: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isDeprecated := tc.info.IsDeprecated()
+
+			assert.Equal(t, tc.expectedIsDeprecated, isDeprecated)
+		})
+	}
+}
+
+func TestFuncInfo_IsDeprecated(t *testing.T) {
+	tests := []struct {
+		name                 string
+		info                 *Func
+		expectedIsDeprecated bool
+	}{
+		{
+			name:                 "NoDoc",
+			info:                 &Func{Name: "Lookup"},
+			expectedIsDeprecated: false,
+		},
+		{
+			name: "NotDeprecated",
+			info: &Func{
+				Name: "Lookup",
+				Doc: &goast.CommentGroup{
+					List: []*goast.Comment{
+						{Text: "// Lookup is not Deprecated: at all."},
+					},
+				},
+			},
+			expectedIsDeprecated: false,
+		},
+		{
+			name: "Deprecated",
+			info: &Func{
+				Name: "Lookup",
+				Doc: &goast.CommentGroup{
+					List: []*goast.Comment{
+						{Text: "// Deprecated: use Find instead."},
+					},
+				},
+			},
+			expectedIsDeprecated: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isDeprecated := tc.info.IsDeprecated()
+
+			assert.Equal(t, tc.expectedIsDeprecated, isDeprecated)
+		})
+	}
+}
+
 func TestFuncInfo_IsMethod(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -94,6 +287,23 @@ func TestFuncInfo_IsMethod(t *testing.T) {
 			},
 			expectedIsMethod: true,
 		},
+		{
+			name: "Method_UnnamedReceiver",
+			info: &Func{
+				RecvType: &goast.Ident{Name: "service"},
+			},
+			expectedIsMethod: true,
+		},
+		{
+			name: "Method_BlankReceiver",
+			info: &Func{
+				RecvName: "_",
+				RecvType: &goast.StarExpr{
+					X: &goast.Ident{Name: "service"},
+				},
+			},
+			expectedIsMethod: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -105,189 +315,1675 @@ func TestFuncInfo_IsMethod(t *testing.T) {
 	}
 }
 
-func TestParseOptions_MatchType(t *testing.T) {
+func TestFuncInfo_Receiver(t *testing.T) {
 	tests := []struct {
-		name            string
-		opts            ParseOptions
-		typeName        *goast.Ident
-		expectedMatched bool
+		name               string
+		info               *Func
+		expectedName       string
+		expectedTypeName   string
+		expectedTypeParams []string
+		expectedIsPointer  bool
 	}{
 		{
-			name:            "Matched_NoFilter",
-			opts:            ParseOptions{},
-			typeName:        &goast.Ident{Name: "Request"},
-			expectedMatched: true,
+			name:               "Function",
+			info:               &Func{},
+			expectedName:       "",
+			expectedTypeName:   "",
+			expectedTypeParams: []string{},
+			expectedIsPointer:  false,
 		},
 		{
-			name: "Matched_Name",
-			opts: ParseOptions{
-				TypeFilter: TypeFilter{
-					Names: []string{"Response"},
-				},
+			name: "ValueReceiver",
+			info: &Func{
+				RecvName: "s",
+				RecvType: &goast.Ident{Name: "service"},
 			},
-			typeName:        &goast.Ident{Name: "Response"},
-			expectedMatched: true,
+			expectedName:       "s",
+			expectedTypeName:   "service",
+			expectedTypeParams: []string{},
+			expectedIsPointer:  false,
 		},
 		{
-			name: "Matched_Regexp",
-			opts: ParseOptions{
-				TypeFilter: TypeFilter{
-					Regexp: regexp.MustCompile(`Service$`),
+			name: "PointerReceiver",
+			info: &Func{
+				RecvName: "s",
+				RecvType: &goast.StarExpr{
+					X: &goast.Ident{Name: "service"},
 				},
 			},
-			typeName:        &goast.Ident{Name: "ExampleService"},
-			expectedMatched: true,
+			expectedName:       "s",
+			expectedTypeName:   "service",
+			expectedTypeParams: []string{},
+			expectedIsPointer:  true,
 		},
 		{
-			name: "NotMatched",
-			opts: ParseOptions{
-				TypeFilter: TypeFilter{
-					Names:  []string{"Request", "Response"},
-					Regexp: regexp.MustCompile(`Service$`),
+			name: "GenericReceiver",
+			info: &Func{
+				RecvName: "c",
+				RecvType: &goast.StarExpr{
+					X: &goast.IndexListExpr{
+						X:       &goast.Ident{Name: "Cache"},
+						Indices: []goast.Expr{&goast.Ident{Name: "K"}, &goast.Ident{Name: "V"}},
+					},
 				},
 			},
-			typeName:        &goast.Ident{Name: "service"},
-			expectedMatched: false,
+			expectedName:       "c",
+			expectedTypeName:   "Cache",
+			expectedTypeParams: []string{"K", "V"},
+			expectedIsPointer:  true,
 		},
 		{
-			name: "Matched_Exported",
-			opts: ParseOptions{
-				TypeFilter: TypeFilter{
-					Exported: true,
+			name: "GenericReceiver_SingleParam",
+			info: &Func{
+				RecvName: "s",
+				RecvType: &goast.IndexExpr{
+					X:     &goast.Ident{Name: "Set"},
+					Index: &goast.Ident{Name: "T"},
 				},
 			},
-			typeName:        &goast.Ident{Name: "Client"},
-			expectedMatched: true,
+			expectedName:       "s",
+			expectedTypeName:   "Set",
+			expectedTypeParams: []string{"T"},
+			expectedIsPointer:  false,
 		},
 		{
-			name: "NotMatched_Unexported",
-			opts: ParseOptions{
-				TypeFilter: TypeFilter{
-					Exported: true,
+			name: "UnnamedPointerReceiver",
+			info: &Func{
+				RecvType: &goast.ParenExpr{
+					X: &goast.StarExpr{
+						X: &goast.Ident{Name: "service"},
+					},
 				},
 			},
-			typeName:        &goast.Ident{Name: "client"},
-			expectedMatched: false,
+			expectedName:       "",
+			expectedTypeName:   "service",
+			expectedTypeParams: []string{},
+			expectedIsPointer:  true,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			matched := tc.opts.matchType(tc.typeName)
+			assert.Equal(t, tc.expectedName, tc.info.ReceiverName())
+			assert.Equal(t, tc.expectedTypeName, tc.info.ReceiverTypeName())
 
-			assert.Equal(t, tc.expectedMatched, matched)
+			typeParams := []string{}
+			for _, p := range tc.info.ReceiverTypeParams() {
+				typeParams = append(typeParams, exprString(p))
+			}
+			assert.Equal(t, tc.expectedTypeParams, typeParams)
+
+			assert.Equal(t, tc.expectedIsPointer, tc.info.ReceiverIsPointer())
 		})
 	}
 }
 
-func TestParser_Parse(t *testing.T) {
+func TestTypeInfo_FullName(t *testing.T) {
 	tests := []struct {
-		name          string
-		consumers     []*Consumer
-		packages      string
-		opts          ParseOptions
-		expectedError string
+		name             string
+		info             *Type
+		expectedFullName string
 	}{
 		{
-			name:          "PathNotExist",
-			packages:      "/foo",
-			opts:          ParseOptions{},
-			expectedError: "stat /foo: no such file or directory",
-		},
-		{
-			name:          "PathNotDirectory",
-			packages:      "./test/valid/main.go",
-			opts:          ParseOptions{},
-			expectedError: `"./test/valid/main.go" is not a directory`,
+			name: "Type",
+			info: &Type{
+				File: File{
+					Package: Package{Name: "lookup", ImportPath: "github.com/octocat/test/lookup"},
+				},
+				Name: "Service",
+			},
+			expectedFullName: "github.com/octocat/test/lookup.Service",
 		},
 		{
-			name:          "InvalidModule",
-			packages:      "./test/invalid_module",
-			opts:          ParseOptions{},
-			expectedError: "invalid go.mod file: no module name found",
+			name: "ExternalTest",
+			info: &Type{
+				File: File{
+					Package:        Package{Name: "lookup_test", ImportPath: "github.com/octocat/test/lookup"},
+					IsExternalTest: true,
+				},
+				Name: "mockService",
+			},
+			expectedFullName: "github.com/octocat/test/lookup_test.mockService",
 		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedFullName, tc.info.FullName())
+		})
+	}
+}
+
+func TestFuncInfo_FullName(t *testing.T) {
+	pkg := Package{Name: "lookup", ImportPath: "github.com/octocat/test/lookup"}
+
+	tests := []struct {
+		name             string
+		info             *Func
+		expectedFullName string
+	}{
 		{
-			name:          "InvalidCode",
-			packages:      "./test/invalid_code",
-			opts:          ParseOptions{},
-			expectedError: "test/invalid_code/main.go:3:11: missing import path (and 10 more errors)",
+			name: "Function",
+			info: &Func{
+				File: File{Package: pkg},
+				Name: "New",
+			},
+			expectedFullName: "github.com/octocat/test/lookup.New",
 		},
 		{
-			name: "Success_SkipPackages",
-			consumers: []*Consumer{
-				{
-					Name:    "tester",
-					Package: func(*Package, string) bool { return false },
-				},
-			},
-			packages: "./test/valid/...",
-			opts: ParseOptions{
-				SkipTestFiles: true,
+			name: "ValueReceiver",
+			info: &Func{
+				File:     File{Package: pkg},
+				Name:     "String",
+				RecvType: &goast.Ident{Name: "ID"},
 			},
-			expectedError: "",
+			expectedFullName: "github.com/octocat/test/lookup.ID.String",
 		},
 		{
-			name: "FilePostFails",
-			consumers: []*Consumer{
-				{
-					Name:      "tester",
-					Package:   func(*Package, string) bool { return true },
-					FilePre:   func(*File, *goast.File) bool { return true },
-					Import:    func(*File, *goast.ImportSpec) {},
-					Struct:    func(*Type, *goast.StructType) {},
-					Interface: func(*Type, *goast.InterfaceType) {},
-					FuncType:  func(*Type, *goast.FuncType) {},
-					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
-					FilePost:  func(*File, *goast.File) error { return errors.New("file error") },
+			name: "PointerReceiver",
+			info: &Func{
+				File:     File{Package: pkg},
+				Name:     "Lookup",
+				RecvName: "s",
+				RecvType: &goast.StarExpr{
+					X: &goast.Ident{Name: "service"},
 				},
 			},
-			packages:      "./test/valid/...",
-			opts:          ParseOptions{},
-			expectedError: "file error",
+			expectedFullName: "github.com/octocat/test/lookup.(*service).Lookup",
 		},
 		{
-			name: "Success_SkipTestFiles",
-			consumers: []*Consumer{
-				{
-					Name:    "tester",
-					Package: func(*Package, string) bool { return true },
-					FilePre: func(*File, *goast.File) bool { return false },
+			name: "GenericReceiver",
+			info: &Func{
+				File:     File{Package: pkg},
+				Name:     "Get",
+				RecvName: "c",
+				RecvType: &goast.StarExpr{
+					X: &goast.IndexListExpr{
+						X:       &goast.Ident{Name: "Cache"},
+						Indices: []goast.Expr{&goast.Ident{Name: "K"}, &goast.Ident{Name: "V"}},
+					},
 				},
 			},
-			packages: "./test/valid/...",
-			opts: ParseOptions{
-				SkipTestFiles: true,
-			},
-			expectedError: "",
+			expectedFullName: "github.com/octocat/test/lookup.(*Cache[...]).Get",
 		},
 		{
-			name: "Success",
-			consumers: []*Consumer{
-				{
-					Name:      "tester",
-					Package:   func(*Package, string) bool { return true },
-					FilePre:   func(*File, *goast.File) bool { return true },
-					Import:    func(*File, *goast.ImportSpec) {},
-					Struct:    func(*Type, *goast.StructType) {},
-					Interface: func(*Type, *goast.InterfaceType) {},
-					FuncType:  func(*Type, *goast.FuncType) {},
-					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
-					FilePost:  func(*File, *goast.File) error { return nil },
+			name: "ExternalTest",
+			info: &Func{
+				File: File{
+					Package: Package{Name: "lookup_test", ImportPath: "github.com/octocat/test/lookup"},
+					IsTest:  true,
 				},
+				Name: "TestLookup",
 			},
-			packages:      "./test/valid/...",
-			opts:          ParseOptions{},
-			expectedError: "",
+			expectedFullName: "github.com/octocat/test/lookup_test.TestLookup",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			p := &parser{
-				ui:        ui.NewNop(),
-				consumers: tc.consumers,
-			}
-
+			assert.Equal(t, tc.expectedFullName, tc.info.FullName())
+		})
+	}
+}
+
+func TestFuncInfo_TestKind(t *testing.T) {
+	tests := []struct {
+		name         string
+		isTest       bool
+		src          string
+		expectedKind TestKind
+	}{
+		{
+			name:         "NotTestFile",
+			isTest:       false,
+			src:          `func TestNew(t *testing.T) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Test",
+			isTest:       true,
+			src:          `func TestNew(t *testing.T) {}`,
+			expectedKind: Test,
+		},
+		{
+			name:         "Test_Underscore",
+			isTest:       true,
+			src:          `func Test_new(t *testing.T) {}`,
+			expectedKind: Test,
+		},
+		{
+			name:         "Test_Lowercase",
+			isTest:       true,
+			src:          `func Testnew(t *testing.T) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Test_WrongParam",
+			isTest:       true,
+			src:          `func TestNew(b *testing.B) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Test_Result",
+			isTest:       true,
+			src:          `func TestNew(t *testing.T) error { return nil }`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "TestMain",
+			isTest:       true,
+			src:          `func TestMain(m *testing.M) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Method",
+			isTest:       true,
+			src:          `func (s *suite) TestNew(t *testing.T) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Generic",
+			isTest:       true,
+			src:          `func TestNew[T any](t *testing.T) {}`,
+			expectedKind: NotATest,
+		},
+		{
+			name:         "Benchmark",
+			isTest:       true,
+			src:          `func BenchmarkNew(b *testing.B) {}`,
+			expectedKind: Benchmark,
+		},
+		{
+			name:         "Fuzz",
+			isTest:       true,
+			src:          `func FuzzNew(f *testing.F) {}`,
+			expectedKind: Fuzz,
+		},
+		{
+			name:         "Example",
+			isTest:       true,
+			src:          `func ExampleService_Lookup() {}`,
+			expectedKind: Example,
+		},
+		{
+			name:         "Example_Params",
+			isTest:       true,
+			src:          `func ExampleNew(t *testing.T) {}`,
+			expectedKind: NotATest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), "", "package lookup\n"+tc.src, goparser.SkipObjectResolution)
+			assert.NoError(t, err)
+
+			fd := file.Decls[0].(*goast.FuncDecl)
+			f := &Func{
+				File:       File{IsTest: tc.isTest},
+				Name:       fd.Name.Name,
+				Type:       fd.Type,
+				Params:     fieldParams(fd.Type.Params),
+				Results:    fieldParams(fd.Type.Results),
+				TypeParams: TypeParamsOf(fd.Type),
+			}
+
+			if fd.Recv != nil {
+				f.RecvType = fd.Recv.List[0].Type
+			}
+
+			assert.Equal(t, tc.expectedKind, f.TestKind())
+		})
+	}
+}
+
+func TestFuncInfo_IsConstructor(t *testing.T) {
+	tests := []struct {
+		name                  string
+		info                  *Func
+		typeName              string
+		expectedIsConstructor bool
+	}{
+		{
+			name: "Unexported",
+			info: &Func{
+				Name: "newService",
+				Results: []Param{
+					{Type: &goast.Ident{Name: "Service"}},
+				},
+			},
+			typeName:              "Service",
+			expectedIsConstructor: false,
+		},
+		{
+			name: "Method",
+			info: &Func{
+				Name:     "New",
+				RecvName: "f",
+				RecvType: &goast.StarExpr{
+					X: &goast.Ident{Name: "factory"},
+				},
+				Results: []Param{
+					{Type: &goast.Ident{Name: "Service"}},
+				},
+			},
+			typeName:              "Service",
+			expectedIsConstructor: false,
+		},
+		{
+			name: "NoResult",
+			info: &Func{
+				Name: "New",
+			},
+			typeName:              "Service",
+			expectedIsConstructor: false,
+		},
+		{
+			name: "OtherType",
+			info: &Func{
+				Name: "NewClient",
+				Results: []Param{
+					{Type: &goast.StarExpr{X: &goast.Ident{Name: "Client"}}},
+				},
+			},
+			typeName:              "Service",
+			expectedIsConstructor: false,
+		},
+		{
+			name: "Constructor",
+			info: &Func{
+				Name: "NewService",
+				Results: []Param{
+					{Type: &goast.StarExpr{X: &goast.Ident{Name: "Service"}}},
+					{Type: &goast.Ident{Name: "error"}},
+				},
+			},
+			typeName:              "Service",
+			expectedIsConstructor: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isConstructor := tc.info.IsConstructor(tc.typeName)
+
+			assert.Equal(t, tc.expectedIsConstructor, isConstructor)
+		})
+	}
+}
+
+func TestFieldParams(t *testing.T) {
+	expr, err := goparser.ParseExpr(`func(a, b string, c int) (*Response, error)`)
+	assert.NoError(t, err)
+	ft := expr.(*goast.FuncType)
+
+	tests := []struct {
+		name           string
+		fields         *goast.FieldList
+		expectedParams []string
+	}{
+		{
+			name:           "Nil",
+			fields:         nil,
+			expectedParams: []string{},
+		},
+		{
+			name:           "Named",
+			fields:         ft.Params,
+			expectedParams: []string{"a string", "b string", "c int"},
+		},
+		{
+			name:           "Unnamed",
+			fields:         ft.Results,
+			expectedParams: []string{" *Response", " error"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []string{}
+			for _, p := range fieldParams(tc.fields) {
+				params = append(params, p.Name+" "+exprString(p.Type))
+			}
+
+			assert.Equal(t, tc.expectedParams, params)
+		})
+	}
+}
+
+func TestFile_TypeOf(t *testing.T) {
+	expr := &goast.Ident{Name: "x"}
+
+	tests := []struct {
+		name         string
+		file         *File
+		expectedType gotypes.Type
+	}{
+		{
+			name:         "NoTypesInfo",
+			file:         &File{},
+			expectedType: nil,
+		},
+		{
+			name: "WithTypesInfo",
+			file: &File{
+				TypesInfo: &gotypes.Info{
+					Types: map[goast.Expr]gotypes.TypeAndValue{
+						expr: {Type: gotypes.Typ[gotypes.Int]},
+					},
+				},
+			},
+			expectedType: gotypes.Typ[gotypes.Int],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			typ := tc.file.TypeOf(expr)
+
+			assert.Equal(t, tc.expectedType, typ)
+		})
+	}
+}
+
+func TestFile_ZeroValue(t *testing.T) {
+	fset := gotoken.NewFileSet()
+	src := `package lookup
+
+type (
+	ID       string
+	Flag     bool
+	Count    int
+	Request  struct{ ID ID }
+	Pair     [2]int
+	Handler  func()
+	Reader   interface{ Read() }
+	Ref      = *Request
+	Index    map[ID]Request
+	Set[T comparable] map[T]struct{}
+)
+
+var (
+	_ ID
+	_ Flag
+	_ Count
+	_ Request
+	_ Pair
+	_ Handler
+	_ Reader
+	_ Ref
+	_ Index
+	_ Set[string]
+	_ (Request)
+)
+
+func Zero[T any]() (_ T) { return }
+`
+	file, err := goparser.ParseFile(fset, "lookup.go", src, 0)
+	assert.NoError(t, err)
+
+	info := &gotypes.Info{
+		Types: make(map[goast.Expr]gotypes.TypeAndValue),
+	}
+
+	conf := gotypes.Config{}
+	_, err = conf.Check("lookup", fset, []*goast.File{file}, info)
+	assert.NoError(t, err)
+
+	// Type expressions of the var declarations and the generic function result
+	exprs := []goast.Expr{}
+	for _, spec := range file.Decls[1].(*goast.GenDecl).Specs {
+		exprs = append(exprs, spec.(*goast.ValueSpec).Type)
+	}
+	exprs = append(exprs, file.Decls[2].(*goast.FuncDecl).Type.Results.List[0].Type)
+
+	tests := []struct {
+		name           string
+		file           *File
+		expectedValues []string
+	}{
+		{
+			name: "NoTypesInfo",
+			file: &File{},
+			expectedValues: []string{
+				"*new(ID)", "*new(Flag)", "*new(Count)", "*new(Request)", "*new(Pair)", "*new(Handler)",
+				"*new(Reader)", "*new(Ref)", "*new(Index)", "*new(Set[string])", "*new(Request)", "*new(T)",
+			},
+		},
+		{
+			name: "WithTypesInfo",
+			file: &File{TypesInfo: info},
+			expectedValues: []string{
+				`""`, "false", "0", "Request{}", "Pair{}", "nil",
+				"nil", "nil", "nil", "nil", "Request{}", "*new(T)",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			values := []string{}
+			for _, expr := range exprs {
+				values = append(values, exprString(tc.file.ZeroValue(expr)))
+			}
+
+			assert.Equal(t, tc.expectedValues, values)
+		})
+	}
+}
+
+func TestFile_ExprString(t *testing.T) {
+	f := &File{
+		FileSet: gotoken.NewFileSet(),
+	}
+
+	expr := &goast.MapType{
+		Key:   goast.NewIdent("string"),
+		Value: &goast.SelectorExpr{X: goast.NewIdent("lookup"), Sel: goast.NewIdent("Request")},
+	}
+
+	assert.Equal(t, "map[string]lookup.Request", f.ExprString(expr))
+}
+
+func TestFile_AppendDecl(t *testing.T) {
+	fset := gotoken.NewFileSet()
+	src := "package lookup\n\nimport \"fmt\"\n\n// Service is the lookup service.\ntype Service struct{} // stateless\n\nfunc (s *Service) String() string {\n\treturn fmt.Sprint(\"service\")\n}\n"
+	file, err := goparser.ParseFile(fset, "lookup.go", src, goparser.ParseComments)
+	assert.NoError(t, err)
+
+	f := &File{FileSet: fset}
+
+	f.AppendDecl(file, &goast.GenDecl{
+		Tok: gotoken.IMPORT,
+		Specs: []goast.Spec{
+			&goast.ImportSpec{Path: &goast.BasicLit{Kind: gotoken.STRING, Value: `"strings"`}},
+		},
+	})
+
+	f.AppendDecl(file, &goast.FuncDecl{
+		Recv: &goast.FieldList{
+			List: []*goast.Field{
+				{Names: []*goast.Ident{goast.NewIdent("s")}, Type: &goast.StarExpr{X: goast.NewIdent("Service")}},
+			},
+		},
+		Name: goast.NewIdent("Upper"),
+		Type: &goast.FuncType{
+			Params:  &goast.FieldList{},
+			Results: &goast.FieldList{List: []*goast.Field{{Type: goast.NewIdent("string")}}},
+		},
+		Body: &goast.BlockStmt{
+			List: []goast.Stmt{
+				&goast.ReturnStmt{
+					Results: []goast.Expr{
+						&goast.CallExpr{
+							Fun:  &goast.SelectorExpr{X: goast.NewIdent("strings"), Sel: goast.NewIdent("ToUpper")},
+							Args: []goast.Expr{&goast.CallExpr{Fun: &goast.SelectorExpr{X: goast.NewIdent("s"), Sel: goast.NewIdent("String")}}},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	buf := new(bytes.Buffer)
+	err = WriteTo(buf, fset, file, "lookup.go")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `package lookup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Service is the lookup service.
+type Service struct{} // stateless
+
+func (s *Service) String() string {
+	return fmt.Sprint("service")
+}
+func (s *Service) Upper() string { return strings.ToUpper(s.String()) }
+`, buf.String())
+}
+
+func TestFile_Source(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"go.mod":    {Data: []byte("module github.com/octocat/test\n")},
+		"lookup.go": {Data: []byte("package lookup\n\ntype Request struct {\n\tID string // identifier\n}\n")},
+	}
+
+	tests := []struct {
+		name           string
+		parse          func(p *parser) error
+		expectedSource string
+	}{
+		{
+			name: "OS",
+			parse: func(p *parser) error {
+				return p.Parse("./test/valid/lookup", ParseOptions{SkipTestFiles: true})
+			},
+			expectedSource: "struct {\n\tID ID `json:\"id\"`\n}",
+		},
+		{
+			name: "FS",
+			parse: func(p *parser) error {
+				return p.ParseFS(mapFS, ".", ParseOptions{})
+			},
+			expectedSource: "struct {\n\tID string // identifier\n}",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var source string
+			var sourceErr error
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name:    "tester",
+						Package: func(*Package, string) bool { return true },
+						FilePre: func(*File, *goast.File) bool { return true },
+						Struct: func(t *Type, st *goast.StructType) {
+							if t.Name == "Request" {
+								source, sourceErr = t.Source(st)
+							}
+						},
+					},
+				},
+			}
+
+			err := tc.parse(p)
+
+			assert.NoError(t, err)
+			assert.NoError(t, sourceErr)
+			assert.Equal(t, tc.expectedSource, source)
+		})
+	}
+
+	t.Run("UnknownPosition", func(t *testing.T) {
+		f := &File{FileSet: gotoken.NewFileSet()}
+		_, err := f.Source(goast.NewIdent("Request"))
+
+		assert.EqualError(t, err, "node position is unknown")
+	})
+}
+
+func TestParseOptions_MatchType(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ParseOptions
+		typeName        *goast.Ident
+		expectedMatched bool
+	}{
+		{
+			name:            "Matched_NoFilter",
+			opts:            ParseOptions{},
+			typeName:        &goast.Ident{Name: "Request"},
+			expectedMatched: true,
+		},
+		{
+			name: "Matched_Name",
+			opts: ParseOptions{
+				TypeFilter: TypeFilter{
+					Names: []string{"Response"},
+				},
+			},
+			typeName:        &goast.Ident{Name: "Response"},
+			expectedMatched: true,
+		},
+		{
+			name: "Matched_Regexp",
+			opts: ParseOptions{
+				TypeFilter: TypeFilter{
+					Regexp: regexp.MustCompile(`Service$`),
+				},
+			},
+			typeName:        &goast.Ident{Name: "ExampleService"},
+			expectedMatched: true,
+		},
+		{
+			name: "NotMatched_ExportedOnly",
+			opts: ParseOptions{
+				ExportedOnly: true,
+			},
+			typeName:        &goast.Ident{Name: "service"},
+			expectedMatched: false,
+		},
+		{
+			name: "NotMatched",
+			opts: ParseOptions{
+				TypeFilter: TypeFilter{
+					Names:  []string{"Request", "Response"},
+					Regexp: regexp.MustCompile(`Service$`),
+				},
+			},
+			typeName:        &goast.Ident{Name: "service"},
+			expectedMatched: false,
+		},
+		{
+			name: "Matched_Exported",
+			opts: ParseOptions{
+				TypeFilter: TypeFilter{
+					Exported: true,
+				},
+			},
+			typeName:        &goast.Ident{Name: "Client"},
+			expectedMatched: true,
+		},
+		{
+			name: "NotMatched_Unexported",
+			opts: ParseOptions{
+				TypeFilter: TypeFilter{
+					Exported: true,
+				},
+			},
+			typeName:        &goast.Ident{Name: "client"},
+			expectedMatched: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			matched := tc.opts.matchType(tc.typeName)
+
+			assert.Equal(t, tc.expectedMatched, matched)
+		})
+	}
+}
+
+func TestIsExportedFunc(t *testing.T) {
+	tests := []struct {
+		name           string
+		f              *Func
+		expectedResult bool
+	}{
+		{
+			name:           "Unexported",
+			f:              &Func{Name: "lookup"},
+			expectedResult: false,
+		},
+		{
+			name:           "Exported",
+			f:              &Func{Name: "Lookup"},
+			expectedResult: true,
+		},
+		{
+			name:           "Method_UnexportedType",
+			f:              &Func{Name: "Lookup", RecvName: "s", RecvType: &goast.StarExpr{X: goast.NewIdent("service")}},
+			expectedResult: false,
+		},
+		{
+			name:           "Method_ExportedType",
+			f:              &Func{Name: "Lookup", RecvName: "s", RecvType: &goast.StarExpr{X: goast.NewIdent("Service")}},
+			expectedResult: true,
+		},
+		{
+			name:           "UnexportedMethod_ExportedType",
+			f:              &Func{Name: "lookup", RecvName: "s", RecvType: goast.NewIdent("Service")},
+			expectedResult: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedResult, isExportedFunc(tc.f))
+		})
+	}
+}
+
+func TestParseOptions_ParseMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         ParseOptions
+		expectedMode goparser.Mode
+	}{
+		{
+			name:         "Default",
+			opts:         ParseOptions{},
+			expectedMode: goparser.SkipObjectResolution | goparser.AllErrors | goparser.ParseComments,
+		},
+		{
+			name:         "ParserMode",
+			opts:         ParseOptions{ParserMode: goparser.Trace},
+			expectedMode: goparser.Trace | goparser.SkipObjectResolution | goparser.AllErrors | goparser.ParseComments,
+		},
+		{
+			name:         "ResolveObjects",
+			opts:         ParseOptions{ParserMode: goparser.DeclarationErrors, ResolveObjects: true},
+			expectedMode: goparser.DeclarationErrors | goparser.AllErrors | goparser.ParseComments,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedMode, tc.opts.parseMode())
+		})
+	}
+}
+
+func TestParser_Parse_ParserMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ParseOptions
+		expectedObjects bool
+	}{
+		{
+			name: "ParserMode",
+			opts: ParseOptions{ParserMode: goparser.DeclarationErrors},
+		},
+		{
+			name:            "ResolveObjects",
+			opts:            ParseOptions{ParserMode: goparser.DeclarationErrors, ResolveObjects: true},
+			expectedObjects: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string]*goast.File{}
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name:    "tester",
+						Package: func(*Package, string) bool { return true },
+						FilePre: func(f *File, file *goast.File) bool {
+							files[f.Name] = file
+							return false
+						},
+					},
+				},
+			}
+
+			assert.NoError(t, p.Parse("./test/valid/...", tc.opts))
+			assert.NotEmpty(t, files)
+
+			// Comments are always parsed
+			assert.NotEmpty(t, files["lookup.go"].Comments)
+
+			for _, file := range files {
+				assert.Equal(t, tc.expectedObjects, file.Scope != nil)
+			}
+		})
+	}
+}
+
+func TestParseOptions_MatchFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ParseOptions
+		fileName        string
+		expectedMatched bool
+	}{
+		{
+			name:            "Matched_NoPattern",
+			opts:            ParseOptions{},
+			fileName:        "lookup.go",
+			expectedMatched: true,
+		},
+		{
+			name: "Matched",
+			opts: ParseOptions{
+				FilePatterns: []string{"*_mock.go", "*_gen.go"},
+			},
+			fileName:        "lookup_gen.go",
+			expectedMatched: true,
+		},
+		{
+			name: "NotMatched",
+			opts: ParseOptions{
+				FilePatterns: []string{"*_mock.go", "*_gen.go"},
+			},
+			fileName:        "lookup.go",
+			expectedMatched: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			matched := tc.opts.matchFile(tc.fileName)
+
+			assert.Equal(t, tc.expectedMatched, matched)
+		})
+	}
+}
+
+func TestParseOptions_MatchPackage(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            ParseOptions
+		importPath      string
+		expectedMatched bool
+	}{
+		{
+			name:            "Matched_NoPattern",
+			opts:            ParseOptions{},
+			importPath:      "github.com/octocat/test/internal/lookup",
+			expectedMatched: true,
+		},
+		{
+			name: "Matched_Include",
+			opts: ParseOptions{
+				IncludePatterns: []string{"github.com/octocat/test/internal/*"},
+			},
+			importPath:      "github.com/octocat/test/internal/lookup",
+			expectedMatched: true,
+		},
+		{
+			name: "NotMatched_Include",
+			opts: ParseOptions{
+				IncludePatterns: []string{"github.com/octocat/test/internal/*"},
+			},
+			importPath:      "github.com/octocat/test/cmd",
+			expectedMatched: false,
+		},
+		{
+			name: "NotMatched_Exclude",
+			opts: ParseOptions{
+				IncludePatterns: []string{"github.com/octocat/test/internal/*"},
+				ExcludePatterns: []string{"github.com/octocat/test/internal/legacy"},
+			},
+			importPath:      "github.com/octocat/test/internal/legacy",
+			expectedMatched: false,
+		},
+		{
+			name: "NotMatched_BadPattern",
+			opts: ParseOptions{
+				IncludePatterns: []string{"["},
+			},
+			importPath:      "github.com/octocat/test",
+			expectedMatched: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			matched := tc.opts.matchPackage(tc.importPath)
+
+			assert.Equal(t, tc.expectedMatched, matched)
+		})
+	}
+}
+
+func TestVisitStructTags(t *testing.T) {
+	expr, err := goparser.ParseExpr("struct {\n\tEmbedded `json:\",inline\"`\n\tA, B string `json:\"ab\"`\n\tC int\n}")
+	assert.NoError(t, err)
+
+	tags := map[string]string{}
+	visitStructTags(expr.(*goast.StructType), func(fieldName string, tag reflect.StructTag) {
+		tags[fieldName] = tag.Get("json")
+	})
+
+	assert.Equal(t, map[string]string{
+		"Embedded": ",inline",
+		"A":        "ab",
+		"B":        "ab",
+	}, tags)
+}
+
+func TestImportsAny(t *testing.T) {
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "main.go", "package main\n\nimport (\n\t\"fmt\"\n\thttp \"net/http\"\n)\n", goparser.ImportsOnly)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		paths           []string
+		expectedImports bool
+	}{
+		{"None", []string{}, false},
+		{"NotImported", []string{"context"}, false},
+		{"Imported", []string{"context", "net/http"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			imports := importsAny(file, tc.paths)
+
+			assert.Equal(t, tc.expectedImports, imports)
+		})
+	}
+}
+
+func TestPackageImports(t *testing.T) {
+	fset := gotoken.NewFileSet()
+
+	main, err := goparser.ParseFile(fset, "main.go", "package main\n\nimport (\n\t\"fmt\"\n\thttp \"net/http\"\n)\n", goparser.ImportsOnly)
+	assert.NoError(t, err)
+
+	server, err := goparser.ParseFile(fset, "server.go", "package main\n\nimport (\n\t\"context\"\n\t\"net/http\"\n)\n", goparser.ImportsOnly)
+	assert.NoError(t, err)
+
+	mainTest, err := goparser.ParseFile(fset, "main_test.go", "package main\n\nimport \"testing\"\n", goparser.ImportsOnly)
+	assert.NoError(t, err)
+
+	random, err := goparser.ParseFile(fset, "random.go", "package main\n\n// #include <stdlib.h>\nimport \"C\"\n\nimport \"unsafe\"\n", goparser.ImportsOnly)
+	assert.NoError(t, err)
+
+	pkgFiles := map[string]*goast.File{
+		"main.go":      main,
+		"server.go":    server,
+		"main_test.go": mainTest,
+		"random.go":    random,
+	}
+
+	tests := []struct {
+		name            string
+		includeTests    bool
+		includeCgo      bool
+		expectedImports []string
+	}{
+		{"WithoutTests", false, true, []string{"C", "context", "fmt", "net/http", "unsafe"}},
+		{"WithTests", true, true, []string{"C", "context", "fmt", "net/http", "testing", "unsafe"}},
+		{"WithoutCgo", true, false, []string{"context", "fmt", "net/http", "testing"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			imports := packageImports(pkgFiles, tc.includeTests, tc.includeCgo)
+
+			assert.Equal(t, tc.expectedImports, imports)
+		})
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		name              string
+		text              string
+		expectedDirective string
+		expectedArgs      []string
+		expectedOK        bool
+	}{
+		{
+			name:       "Comment",
+			text:       "// go:generate is not a directive",
+			expectedOK: false,
+		},
+		{
+			name:       "Empty",
+			text:       "//go:",
+			expectedOK: false,
+		},
+		{
+			name:              "NoArgs",
+			text:              "//go:noinline",
+			expectedDirective: "go:noinline",
+			expectedArgs:      []string{},
+			expectedOK:        true,
+		},
+		{
+			name:              "Args",
+			text:              "//go:generate go run gen.go -out  gen.go",
+			expectedDirective: "go:generate",
+			expectedArgs:      []string{"go", "run", "gen.go", "-out", "gen.go"},
+			expectedOK:        true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			directive, args, ok := parseDirective(tc.text)
+
+			assert.Equal(t, tc.expectedDirective, directive)
+			assert.Equal(t, tc.expectedArgs, args)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		m            map[string]int
+		expectedKeys []string
+	}{
+		{
+			name:         "Empty",
+			m:            map[string]int{},
+			expectedKeys: []string{},
+		},
+		{
+			name:         "OK",
+			m:            map[string]int{"lookup_test": 3, "lookup": 1, "main": 2},
+			expectedKeys: []string{"lookup", "lookup_test", "main"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := sortedKeys(tc.m)
+
+			assert.Equal(t, tc.expectedKeys, keys)
+		})
+	}
+}
+
+func TestSortedConsumers(t *testing.T) {
+	tests := []struct {
+		name          string
+		consumers     []*Consumer
+		expectedNames []string
+	}{
+		{
+			name:          "Empty",
+			consumers:     []*Consumer{},
+			expectedNames: []string{},
+		},
+		{
+			name: "OK",
+			consumers: []*Consumer{
+				{Name: "methods", Priority: 10},
+				{Name: "first"},
+				{Name: "imports", Priority: -1},
+				{Name: "second"},
+			},
+			expectedNames: []string{"imports", "first", "second", "methods"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			consumers := sortedConsumers(tc.consumers)
+
+			names := []string{}
+			for _, c := range consumers {
+				names = append(names, c.Name)
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name          string
+		consumers     []*Consumer
+		packages      string
+		opts          ParseOptions
+		expectedError string
+	}{
+		{
+			name:          "PathNotExist",
+			packages:      "/foo",
+			opts:          ParseOptions{},
+			expectedError: "stat /foo: no such file or directory",
+		},
+		{
+			name:          "PathNotDirectory",
+			packages:      "./test/valid/main.go",
+			opts:          ParseOptions{},
+			expectedError: `"./test/valid/main.go" is not a directory`,
+		},
+		{
+			name:          "InvalidModule",
+			packages:      "./test/invalid_module",
+			opts:          ParseOptions{},
+			expectedError: "invalid go.mod file: no module name found",
+		},
+		{
+			name:          "InvalidCode",
+			packages:      "./test/invalid_code",
+			opts:          ParseOptions{},
+			expectedError: "test/invalid_code/main.go:3:11: missing import path (and 10 more errors)",
+		},
+		{
+			name:     "InvalidCode_Skipped",
+			packages: "./test/invalid_code",
+			opts: ParseOptions{
+				OnParseError: func(string, error) error { return nil },
+			},
+			expectedError: "",
+		},
+		{
+			name:     "InvalidCode_Aborted",
+			packages: "./test/invalid_code",
+			opts: ParseOptions{
+				OnParseError: func(path string, _ error) error { return fmt.Errorf("bad file: %s", path) },
+			},
+			expectedError: "bad file: test/invalid_code/main.go",
+		},
+		{
+			name: "TypeCheckFails",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+				},
+			},
+			packages: "./test/invalid_types",
+			opts: ParseOptions{
+				TypeCheck: true,
+			},
+			expectedError: `test/invalid_types/main.go:4:14: cannot use "Hello, World!" (untyped string constant) as int value in variable declaration`,
+		},
+		{
+			name: "Success_SkipPackages",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return false },
+				},
+			},
+			packages: "./test/valid/...",
+			opts: ParseOptions{
+				SkipTestFiles: true,
+			},
+			expectedError: "",
+		},
+		{
+			name: "FilePostFails",
+			consumers: []*Consumer{
+				{
+					Name:      "tester",
+					Package:   func(*Package, string) bool { return true },
+					FilePre:   func(*File, *goast.File) bool { return true },
+					Import:    func(*File, *goast.ImportSpec) {},
+					Struct:    func(*Type, *goast.StructType) {},
+					StructTag: func(*Type, string, reflect.StructTag) {},
+					Interface: func(*Type, *goast.InterfaceType) {},
+					FuncType:  func(*Type, *goast.FuncType) {},
+					Alias:     func(*Type, goast.Expr) {},
+					Named:     func(*Type, goast.Expr) {},
+					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
+					Directive: func(*File, string, []string, gotoken.Position) {},
+					FilePost:  func(*File, *goast.File) error { return errors.New("file error") },
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: `consumer "tester" failed on main.go: file error`,
+		},
+		{
+			name: "Success_SkipTestFiles",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return false },
+				},
+			},
+			packages: "./test/valid/...",
+			opts: ParseOptions{
+				SkipTestFiles: true,
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success",
+			consumers: []*Consumer{
+				{
+					Name:      "tester",
+					Package:   func(*Package, string) bool { return true },
+					FilePre:   func(*File, *goast.File) bool { return true },
+					Import:    func(*File, *goast.ImportSpec) {},
+					Struct:    func(*Type, *goast.StructType) {},
+					StructTag: func(*Type, string, reflect.StructTag) {},
+					Interface: func(*Type, *goast.InterfaceType) {},
+					FuncType:  func(*Type, *goast.FuncType) {},
+					Alias:     func(*Type, goast.Expr) {},
+					Named:     func(*Type, goast.Expr) {},
+					FuncDecl:  func(*Func, *goast.FuncType, *goast.BlockStmt) {},
+					Directive: func(*File, string, []string, gotoken.Position) {},
+					FilePost:  func(*File, *goast.File) error { return nil },
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_ExcludePackages",
+			consumers: []*Consumer{
+				{
+					Name: "tester",
+					Package: func(p *Package, _ string) bool {
+						if p.ImportPath == "github.com/octocat/test/lookup" {
+							panic("excluded package visited")
+						}
+						return true
+					},
+				},
+			},
+			packages: "./test/valid/...",
+			opts: ParseOptions{
+				ExcludePatterns: []string{"github.com/octocat/test/*"},
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success_Alias",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Struct: func(t *Type, _ *goast.StructType) {
+						if t.IsAlias {
+							panic("unexpected alias " + t.Name)
+						}
+					},
+					Alias: func(t *Type, expr goast.Expr) {
+						if !t.IsAlias || t.Name != "ID" || InferName(expr) != "string" {
+							panic("unexpected alias " + t.Name)
+						}
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_Named",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Named: func(t *Type, expr goast.Expr) {
+						if t.IsAlias || t.Name != "Status" || InferName(expr) != "int" {
+							panic("unexpected named type " + t.Name)
+						}
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_StructTag",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					StructTag: func(t *Type, fieldName string, tag reflect.StructTag) {
+						if t.Name != "Request" || fieldName != "ID" || tag.Get("json") != "id" {
+							panic("unexpected struct tag " + t.Name + "." + fieldName)
+						}
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_TestFiles",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.IsTest != (f.Name == "lookup_test.go") {
+							panic("unexpected test file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_Directive",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Directive: func(f *File, directive string, args []string, pos gotoken.Position) {
+						if f.Name != "lookup.go" || directive != "go:generate" || len(args) != 2 || pos.Line != 1 {
+							panic("unexpected directive " + directive)
+						}
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_Synopsis",
+			consumers: []*Consumer{
+				{
+					Name: "tester",
+					Package: func(p *Package, name string) bool {
+						if name == "lookup" && p.Synopsis != "Package lookup provides a service for looking up resources." {
+							panic("unexpected synopsis " + p.Synopsis)
+						}
+						return true
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_Cgo",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.IsCgo != (f.Name == "random.go") {
+							panic("unexpected cgo file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages:      "./test/cgo",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_SkipCgoFiles",
+			consumers: []*Consumer{
+				{
+					Name: "tester",
+					Package: func(p *Package, _ string) bool {
+						if slices.Contains(p.Imports, "C") {
+							panic("unexpected import C in " + p.Name)
+						}
+						return true
+					},
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.IsCgo {
+							panic("unexpected cgo file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages: "./test/cgo",
+			opts: ParseOptions{
+				SkipCgoFiles: true,
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success_SkipGeneratedFiles",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.Name != "model.go" {
+							panic("unexpected file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages: "./test/generated",
+			opts: ParseOptions{
+				SkipGeneratedFiles: true,
+				TypeCheck:          true,
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success_GenDecl",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					GenDecl: func(_ *File, d *goast.GenDecl) bool {
+						return d.Tok != gotoken.TYPE
+					},
+					Import: func(*File, *goast.ImportSpec) {},
+					Struct: func(t *Type, _ *goast.StructType) {
+						panic("unexpected struct " + t.Name)
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_NoBody",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					FuncDecl: func(f *Func, _ *goast.FuncType, body *goast.BlockStmt) {
+						if f.HasBody != (f.Name == "Sub") || f.HasBody != (body != nil) {
+							panic("unexpected body for " + f.Name)
+						}
+					},
+				},
+			},
+			packages:      "./test/asm",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_FilePatterns",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.Name != "model_gen.go" {
+							panic("unexpected file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages: "./test/generated",
+			opts: ParseOptions{
+				FilePatterns: []string{"*_gen.go"},
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success_Imports",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Imports: []string{"context"},
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.Name != "lookup.go" {
+							panic("unexpected file " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_Doc",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Struct: func(t *Type, _ *goast.StructType) {
+						if t.Name == "Request" && t.Doc.Text() != "Request is the lookup request.\n" {
+							panic("unexpected doc for " + t.Name)
+						}
+					},
+					FuncDecl: func(f *Func, _ *goast.FuncType, _ *goast.BlockStmt) {
+						if f.Name == "New" && f.Doc.Text() != "New create a new lookup service.\n" {
+							panic("unexpected doc for " + f.Name)
+						}
+					},
+				},
+			},
+			packages:      "./test/valid/...",
+			opts:          ParseOptions{},
+			expectedError: "",
+		},
+		{
+			name: "Success_AllPlatforms",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						if f.BuildConstraint != nil {
+							panic("unexpected build constraint for " + f.Name)
+						}
+						if f.BuildTags == nil {
+							panic("no build tags for " + f.Name)
+						}
+						return true
+					},
+				},
+			},
+			packages: "./test/valid/...",
+			opts: ParseOptions{
+				AllPlatforms: true,
+			},
+			expectedError: "",
+		},
+		{
+			name: "Success_TypeCheck",
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Struct: func(t *Type, s *goast.StructType) {
+						for _, field := range s.Fields.List {
+							if t.TypeOf(field.Type) == nil {
+								panic("no type information for " + t.Name)
+							}
+						}
+					},
+				},
+			},
+			packages: "./test/valid/...",
+			opts: ParseOptions{
+				TypeCheck: true,
+			},
+			expectedError: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &parser{
+				ui:        ui.NewNop(),
+				consumers: tc.consumers,
+			}
+
 			err := p.Parse(tc.packages, tc.opts)
 
 			if tc.expectedError == "" {
@@ -298,3 +1994,781 @@ func TestParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_Parse_Vendor(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module github.com/octocat/test\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "vendor", "github.com", "octocat", "dep"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "vendor", "github.com", "octocat", "dep", "dep.go"), []byte("package dep\n"), 0644))
+
+	tests := []struct {
+		name                string
+		opts                ParseOptions
+		expectedImportPaths []string
+	}{
+		{
+			name:                "SkipVendor",
+			opts:                ParseOptions{},
+			expectedImportPaths: []string{},
+		},
+		{
+			name: "ParseVendor",
+			opts: ParseOptions{
+				ParseVendor: true,
+			},
+			expectedImportPaths: []string{"github.com/octocat/dep"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			importPaths := []string{}
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name: "tester",
+						Package: func(p *Package, _ string) bool {
+							importPaths = append(importPaths, p.ImportPath)
+							return false
+						},
+					},
+				},
+			}
+
+			err := p.Parse(root+"/...", tc.opts)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedImportPaths, importPaths)
+		})
+	}
+}
+
+func TestParser_Parse_NestedModules(t *testing.T) {
+	packages := []string{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name: "tester",
+				Package: func(p *Package, _ string) bool {
+					packages = append(packages, p.Module.Name+" "+p.ImportPath)
+					return false
+				},
+			},
+		},
+	}
+
+	err := p.Parse("./test/nested/...", ParseOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"github.com/octocat/nested github.com/octocat/nested",
+		"github.com/octocat/nested github.com/octocat/nested/internal",
+		"github.com/octocat/tools github.com/octocat/tools",
+		"github.com/octocat/tools github.com/octocat/tools/gen",
+	}, packages)
+}
+
+func TestParser_Parse_NoModule(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "src", "github.com", "octocat", "test")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "lookup"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lookup", "lookup.go"), []byte("package lookup\n"), 0644))
+
+	tests := []struct {
+		name                string
+		opts                ParseOptions
+		expectedError       string
+		expectedImportPaths []string
+	}{
+		{
+			name:                "NoModule",
+			opts:                ParseOptions{},
+			expectedError:       root + ": go.mod not found",
+			expectedImportPaths: []string{},
+		},
+		{
+			name: "AllowNoModule",
+			opts: ParseOptions{
+				AllowNoModule: true,
+			},
+			expectedImportPaths: []string{"github.com/octocat/test/lookup"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			importPaths := []string{}
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name: "tester",
+						Package: func(p *Package, _ string) bool {
+							importPaths = append(importPaths, p.ImportPath)
+							return false
+						},
+					},
+				},
+			}
+
+			err := p.Parse(root+"/...", tc.opts)
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+			assert.Equal(t, tc.expectedImportPaths, importPaths)
+		})
+	}
+}
+
+func TestParser_Parse_Progress(t *testing.T) {
+	type progress struct {
+		current, total int
+		pkg            string
+	}
+
+	calls := []progress{}
+
+	p := &parser{
+		ui:        ui.NewNop(),
+		consumers: []*Consumer{},
+	}
+
+	err := p.Parse("./test/valid/...", ParseOptions{
+		Progress: func(current, total int, pkg string) {
+			calls = append(calls, progress{current, total, pkg})
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []progress{
+		{1, 2, "github.com/octocat/test"},
+		{2, 2, "github.com/octocat/test/lookup"},
+	}, calls)
+
+	t.Run("NoGoFiles", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/progress\n"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("# Progress\n"), 0644))
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "util"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "util", "util.go"), []byte("package util\n"), 0644))
+
+		calls = []progress{}
+		err := p.Parse(dir+"/...", ParseOptions{
+			Progress: func(current, total int, pkg string) {
+				calls = append(calls, progress{current, total, pkg})
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []progress{
+			{1, 2, "example.com/progress"},
+			{2, 2, "example.com/progress/util"},
+		}, calls)
+	})
+}
+
+func TestParsePackages(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		opts          ParseOptions
+		expectedError string
+		expectedFiles map[string][]string
+	}{
+		{
+			name:          "InvalidPath",
+			path:          "./test/null",
+			opts:          ParseOptions{},
+			expectedError: "stat ./test/null: no such file or directory",
+		},
+		{
+			name: "Success",
+			path: "./test/valid/...",
+			opts: ParseOptions{},
+			expectedFiles: map[string][]string{
+				"github.com/octocat/test":        {"test/valid/main.go"},
+				"github.com/octocat/test/lookup": {"test/valid/lookup/lookup.go", "test/valid/lookup/lookup_test.go"},
+			},
+		},
+		{
+			name: "SkipTestFiles",
+			path: "./test/valid/...",
+			opts: ParseOptions{SkipTestFiles: true},
+			expectedFiles: map[string][]string{
+				"github.com/octocat/test":        {"test/valid/main.go"},
+				"github.com/octocat/test/lookup": {"test/valid/lookup/lookup.go"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgs, err := ParsePackages(tc.path, tc.opts)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, pkgs)
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, pkgs, len(tc.expectedFiles))
+				for importPath, files := range tc.expectedFiles {
+					assert.Contains(t, pkgs, importPath)
+					assert.Equal(t, files, sortedKeys(pkgs[importPath].Files))
+				}
+			}
+		})
+	}
+}
+
+func TestParser_Parse_AnonymousTypes(t *testing.T) {
+	types := []string{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(*File, *goast.File) bool { return true },
+				Struct: func(t *Type, _ *goast.StructType) {
+					types = append(types, t.Name)
+				},
+				AnonStruct: func(t *Type, st *goast.StructType) {
+					types = append(types, fmt.Sprintf("%s(%d)", t.Name, len(st.Fields.List)))
+				},
+				AnonInterface: func(t *Type, it *goast.InterfaceType) {
+					types = append(types, fmt.Sprintf("%s(%d)", t.Name, len(it.Methods.List)))
+				},
+			},
+		},
+	}
+
+	err := p.Parse("./test/anonymous", ParseOptions{
+		IncludeAnonymousTypes: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Config.Server(2)", "Config.Logger(1)", "Config",
+		"defaults(1)", "options(1)",
+		"settings.Cache(1)", "settings.Cache2(1)", "settings",
+	}, types)
+
+	// Anonymous types are filtered like the named types
+	types = []string{}
+	err = p.Parse("./test/anonymous", ParseOptions{
+		IncludeAnonymousTypes: true,
+		ExportedOnly:          true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Config.Server(2)", "Config.Logger(1)", "Config"}, types)
+}
+
+func TestListPackages(t *testing.T) {
+	tests := []struct {
+		name             string
+		path             string
+		opts             ParseOptions
+		expectedError    string
+		expectedPackages []string
+	}{
+		{
+			name:          "InvalidPath",
+			path:          "./test/null",
+			opts:          ParseOptions{},
+			expectedError: "stat ./test/null: no such file or directory",
+		},
+		{
+			name:             "NoPackage",
+			path:             "./test/invalid_module",
+			opts:             ParseOptions{},
+			expectedPackages: []string{},
+		},
+		{
+			name:             "Single",
+			path:             "./test/valid",
+			opts:             ParseOptions{},
+			expectedPackages: []string{"."},
+		},
+		{
+			name:             "Recursive",
+			path:             "./test/nested/...",
+			opts:             ParseOptions{},
+			expectedPackages: []string{".", "internal", "tools", filepath.Join("tools", "gen")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgs, err := ListPackages(tc.path, tc.opts)
+
+			if tc.expectedError != "" {
+				assert.Nil(t, pkgs)
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPackages, pkgs)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_ExportedOnly(t *testing.T) {
+	names := []string{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:      "tester",
+				Package:   func(*Package, string) bool { return true },
+				FilePre:   func(*File, *goast.File) bool { return true },
+				Struct:    func(t *Type, _ *goast.StructType) { names = append(names, t.Name) },
+				Interface: func(t *Type, _ *goast.InterfaceType) { names = append(names, t.Name) },
+				FuncDecl:  func(f *Func, _ *goast.FuncType, _ *goast.BlockStmt) { names = append(names, f.Name) },
+			},
+		},
+	}
+
+	err := p.Parse("./test/valid/lookup", ParseOptions{
+		SkipTestFiles: true,
+		ExportedOnly:  true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Request", "Response", "Service", "New"}, names)
+}
+
+func TestParser_Parse_Receivers(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module github.com/octocat/test\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "server.go"), []byte(`package server
+
+type Server struct{}
+
+func New() *Server { return &Server{} }
+
+func (s *Server) Start() error { return nil }
+
+func (*Server) Close() error { return nil }
+
+func (_ Server) String() string { return "server" }
+`), 0644))
+
+	methods := []string{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(*File, *goast.File) bool { return true },
+				FuncDecl: func(f *Func, _ *goast.FuncType, _ *goast.BlockStmt) {
+					methods = append(methods, fmt.Sprintf("%s:%t:%q", f.Name, f.IsMethod(), f.RecvName))
+				},
+			},
+		},
+	}
+
+	err := p.Parse(root, ParseOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`New:false:""`,
+		`Start:true:"s"`,
+		`Close:true:""`,
+		`String:true:"_"`,
+	}, methods)
+}
+
+func TestParser_Parse_RecoverPanics(t *testing.T) {
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(*File, *goast.File) bool { return true },
+				Struct: func(t *Type, _ *goast.StructType) {
+					if t.Name == "Response" {
+						panic("unexpected struct")
+					}
+				},
+			},
+		},
+	}
+
+	err := p.Parse("./test/valid/lookup", ParseOptions{
+		SkipTestFiles: true,
+		RecoverPanics: true,
+	})
+
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, "tester", pe.Consumer)
+	assert.Equal(t, "lookup.go", filepath.Base(pe.Position.Filename))
+	assert.Equal(t, 21, pe.Position.Line)
+	assert.Equal(t, "unexpected struct", pe.Value)
+
+	var skipped []string
+	err = p.Parse("./test/valid/lookup", ParseOptions{
+		SkipTestFiles: true,
+		RecoverPanics: true,
+		OnParseError: func(path string, err error) error {
+			skipped = append(skipped, filepath.Base(path))
+			return nil
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lookup.go"}, skipped)
+
+	assert.Panics(t, func() {
+		_ = p.Parse("./test/valid/lookup", ParseOptions{SkipTestFiles: true})
+	})
+}
+
+func TestParser_Parse_StopParsing(t *testing.T) {
+	t.Run("FilePost", func(t *testing.T) {
+		packages := []string{}
+		files := []string{}
+
+		p := &parser{
+			ui: ui.NewNop(),
+			consumers: []*Consumer{
+				{
+					Name: "finder",
+					Package: func(p *Package, _ string) bool {
+						packages = append(packages, p.ImportPath)
+						return true
+					},
+					FilePre: func(*File, *goast.File) bool { return true },
+					FilePost: func(f *File, _ *goast.File) error {
+						return fmt.Errorf("found %s: %w", f.Name, ErrStopParsing)
+					},
+				},
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					FilePost: func(f *File, _ *goast.File) error {
+						files = append(files, f.Name)
+						return nil
+					},
+				},
+			},
+		}
+
+		err := p.Parse("./test/valid/...", ParseOptions{SkipTestFiles: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"github.com/octocat/test"}, packages)
+		assert.Empty(t, files)
+	})
+
+	t.Run("OnParseError", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"go.mod":           {Data: []byte("module github.com/octocat/test\n")},
+			"invalid/main.go":  {Data: []byte("package main\n\nfunc main {}\n")},
+			"lookup/lookup.go": {Data: []byte("package lookup\n")},
+		}
+
+		packages := []string{}
+		p := &parser{
+			ui: ui.NewNop(),
+			consumers: []*Consumer{
+				{
+					Name: "tester",
+					Package: func(p *Package, _ string) bool {
+						packages = append(packages, p.ImportPath)
+						return true
+					},
+				},
+			},
+		}
+
+		err := p.ParseFS(fsys, "./...", ParseOptions{
+			OnParseError: func(string, error) error {
+				return ErrStopParsing
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Empty(t, packages)
+	})
+}
+
+func TestParser_Parse_Trace(t *testing.T) {
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name:    "tester",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(*File, *goast.File) bool { return true },
+			},
+		},
+	}
+
+	root, err := filepath.Abs("./test/valid")
+	assert.NoError(t, err)
+
+	events := []string{}
+	err = p.Parse("./test/valid/...", ParseOptions{
+		SkipTestFiles: true,
+		TypeCheck:     true,
+		Trace: func(event, dir string, dur time.Duration) {
+			rel, err := filepath.Rel(root, dir)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, dur, time.Duration(0))
+			events = append(events, event+" "+rel)
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"read .", "parse .", "typecheck .", "dispatch .",
+		"read lookup", "parse lookup", "typecheck lookup", "dispatch lookup",
+	}, events)
+}
+
+func TestParser_Parse_TypeCheck(t *testing.T) {
+	t.Run("ModuleImports", func(t *testing.T) {
+		// The module is outside the working directory, so imports cannot be resolved relative to it
+		root := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/tc\n\ngo 1.21\n"), 0644))
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, "a"), 0755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, "b"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "a.go"), []byte("package a\n\nimport \"example.com/tc/b\"\n\ntype A struct {\n\tB b.B\n}\n"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "b", "b.go"), []byte("package b\n\ntype B struct{}\n"), 0644))
+
+		types := []string{}
+		p := &parser{
+			ui: ui.NewNop(),
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(*File, *goast.File) bool { return true },
+					Struct: func(t *Type, s *goast.StructType) {
+						for _, field := range s.Fields.List {
+							if typ := t.TypeOf(field.Type); typ != nil {
+								types = append(types, typ.String())
+							}
+						}
+					},
+				},
+			},
+		}
+
+		err := p.Parse(root+"/...", ParseOptions{TypeCheck: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example.com/tc/b.B"}, types)
+	})
+
+	t.Run("OnParseError", func(t *testing.T) {
+		var dirs []string
+		var typed bool
+
+		p := &parser{
+			ui: ui.NewNop(),
+			consumers: []*Consumer{
+				{
+					Name:    "tester",
+					Package: func(*Package, string) bool { return true },
+					FilePre: func(f *File, _ *goast.File) bool {
+						typed = f.TypesInfo != nil
+						return true
+					},
+				},
+			},
+		}
+
+		err := p.Parse("./test/invalid_types", ParseOptions{
+			TypeCheck: true,
+			OnParseError: func(path string, err error) error {
+				dirs = append(dirs, filepath.Base(path))
+				return nil
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"invalid_types"}, dirs)
+		assert.True(t, typed)
+	})
+}
+
+func TestParser_Parse_Directory(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module github.com/octocat/test\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "docs", "README.md"), []byte("# Docs\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "lookup"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lookup", "lookup.go"), []byte("package lookup\n"), 0644))
+
+	dirs := []string{}
+	packages := []string{}
+
+	p := &parser{
+		ui: ui.NewNop(),
+		consumers: []*Consumer{
+			{
+				Name: "tester",
+				Directory: func(absDir, relDir string) {
+					assert.Equal(t, filepath.Join(root, relDir), absDir)
+					dirs = append(dirs, relDir)
+				},
+				Package: func(p *Package, _ string) bool {
+					packages = append(packages, p.ImportPath)
+					return false
+				},
+			},
+		},
+	}
+
+	err := p.Parse(root+"/...", ParseOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".", "docs", "empty", "lookup"}, dirs)
+	assert.Equal(t, []string{"github.com/octocat/test", "github.com/octocat/test/lookup"}, packages)
+}
+
+func TestParser_Parse_MergeTestPackages(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module github.com/octocat/test\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lookup.go"), []byte("package lookup\n\ntype Request struct{}\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lookup_test.go"), []byte("package lookup\n\ntype request struct{}\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "example_test.go"), []byte("package lookup_test\n\ntype example struct{}\n"), 0644))
+
+	tests := []struct {
+		name             string
+		opts             ParseOptions
+		expectedPackages []string
+		expectedFiles    []string
+	}{
+		{
+			name:             "Separate",
+			opts:             ParseOptions{},
+			expectedPackages: []string{"lookup", "lookup_test"},
+			expectedFiles:    []string{"lookup_test:example_test.go:true", "lookup:lookup.go:false", "lookup:lookup_test.go:false"},
+		},
+		{
+			name:             "Merged",
+			opts:             ParseOptions{MergeTestPackages: true},
+			expectedPackages: []string{"lookup"},
+			expectedFiles:    []string{"lookup:example_test.go:true", "lookup:lookup.go:false", "lookup:lookup_test.go:false"},
+		},
+		{
+			name:             "Merged_TypeCheck",
+			opts:             ParseOptions{MergeTestPackages: true, TypeCheck: true},
+			expectedPackages: []string{"lookup"},
+			expectedFiles:    []string{"lookup:example_test.go:true", "lookup:lookup.go:false", "lookup:lookup_test.go:false"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			packages := []string{}
+			files := []string{}
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name: "tester",
+						Package: func(_ *Package, name string) bool {
+							packages = append(packages, name)
+							return true
+						},
+						FilePre: func(*File, *goast.File) bool { return true },
+						Struct: func(typ *Type, st *goast.StructType) {
+							if tc.opts.TypeCheck {
+								assert.NotNil(t, typ.TypeOf(st))
+							}
+							files = append(files, fmt.Sprintf("%s:%s:%t", typ.Package.Name, typ.File.Name, typ.IsExternalTest))
+						},
+					},
+				},
+			}
+
+			err := p.Parse(root, tc.opts)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPackages, packages)
+			assert.ElementsMatch(t, tc.expectedFiles, files)
+		})
+	}
+}
+
+func TestParser_Parse_ModuleResolver(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "BUILD"), []byte(""), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "lookup"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lookup", "lookup.go"), []byte("package lookup\n"), 0644))
+
+	tests := []struct {
+		name             string
+		resolver         func(string) (string, error)
+		expectedPackages []string
+		expectedError    string
+	}{
+		{
+			name:          "Error",
+			resolver:      func(string) (string, error) { return "", errors.New("no BUILD file") },
+			expectedError: "no BUILD file",
+		},
+		{
+			name: "Success",
+			resolver: func(dir string) (string, error) {
+				if dir != root {
+					return "", fmt.Errorf("unexpected directory: %s", dir)
+				}
+				return "github.com/octocat/monorepo", nil
+			},
+			expectedPackages: []string{"github.com/octocat/monorepo:github.com/octocat/monorepo/lookup"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			packages := []string{}
+
+			p := &parser{
+				ui: ui.NewNop(),
+				consumers: []*Consumer{
+					{
+						Name: "tester",
+						Package: func(p *Package, _ string) bool {
+							packages = append(packages, p.Module.Name+":"+p.ImportPath)
+							return false
+						},
+					},
+				},
+			}
+
+			err := p.Parse(root+"/...", ParseOptions{ModuleResolver: tc.resolver})
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPackages, packages)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
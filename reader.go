@@ -1,58 +1,263 @@
 package parser
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
-// getModuleName returns the name of go module from a given path.
-func getModuleName(path string) (string, error) {
-	path, err := filepath.Abs(path)
+var (
+	// ErrModuleNotFound is returned when no go.mod file is found in a path or any of its parent directories.
+	ErrModuleNotFound = errors.New("go.mod not found")
+
+	// ErrInvalidModule is returned when a go.mod file cannot be parsed or has no module name.
+	ErrInvalidModule = errors.New("invalid go.mod file")
+)
+
+// moduleFile contains information about a parsed go.mod file.
+type moduleFile struct {
+	*modfile.File
+	// Dir is the absolute path to the directory containing the go.mod file.
+	Dir string
+}
+
+// readModuleFile finds and parses the go.mod file for a given path.
+// If there is no go.mod file in the path, parent directories will be searched.
+func readModuleFile(fsys fileSystem, path string) (*moduleFile, error) {
+	dir, filename, data, err := findModuleFile(fsys, path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	filename := filepath.Join(path, "go.mod")
+	return parseModuleFile(dir, filename, data)
+}
 
-	if _, err := os.Stat(filename); err != nil {
-		if os.IsNotExist(err) {
-			if parent := filepath.Dir(path); parent != "/" {
-				return getModuleName(parent)
-			}
+// findModuleFile finds the go.mod file for a given path and returns its directory, file name, and content.
+// If there is no go.mod file in the path, parent directories will be searched.
+func findModuleFile(fsys fileSystem, path string) (string, string, []byte, error) {
+	absPath, err := fsys.Abs(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	dir := absPath
+	for {
+		filename := filepath.Join(dir, "go.mod")
+
+		data, err := fsys.ReadFile(filename)
+		if err == nil {
+			return dir, filename, data, nil
 		}
-		return "", err
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil, fmt.Errorf("%s: %w", path, ErrModuleNotFound)
+		}
+		dir = parent
+	}
+}
+
+// parseModuleName returns the module path declared in the content of a go.mod file.
+// The common single-line form of the module directive is read without parsing the whole file.
+// Otherwise (e.g. the block form), the file is parsed to find the module path or to report why it is invalid.
+func parseModuleName(content []byte) (string, error) {
+	if name := modfile.ModulePath(content); name != "" && module.CheckImportPath(name) == nil {
+		return name, nil
 	}
 
-	f, err := os.Open(filename)
+	mf, err := modfile.Parse("go.mod", content, nil)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %s", ErrInvalidModule, err)
+	}
+
+	if mf.Module == nil || mf.Module.Mod.Path == "" {
+		return "", fmt.Errorf("%w: no module name found", ErrInvalidModule)
+	}
+
+	return mf.Module.Mod.Path, nil
+}
+
+func parseModuleFile(dir, filename string, data []byte) (*moduleFile, error) {
+	name, err := parseModuleName(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mf, err := modfile.Parse(filename, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidModule, err)
+	}
+
+	if mf.Module == nil || mf.Module.Mod.Path != name {
+		return nil, fmt.Errorf("%w: module name %q not found", ErrInvalidModule, name)
+	}
+
+	return &moduleFile{
+		File: mf,
+		Dir:  dir,
+	}, nil
+}
+
+// Name returns the module path declared in the go.mod file.
+func (m *moduleFile) Name() string {
+	return m.Module.Mod.Path
+}
+
+// replacedImportPath resolves the import path for a package directory that falls under a local replacement.
+// A local replacement is a replace directive whose target is a directory path (e.g. replace example.com/foo => ./foo).
+func (m *moduleFile) replacedImportPath(absDir string) (string, bool) {
+	for _, r := range m.Replace {
+		if r.New.Version != "" || !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+
+		replaceDir := r.New.Path
+		if !filepath.IsAbs(replaceDir) {
+			replaceDir = filepath.Join(m.Dir, replaceDir)
+		}
+
+		rel, err := filepath.Rel(replaceDir, absDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		return path.Join(r.Old.Path, filepath.ToSlash(rel)), true
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		if line := scanner.Text(); strings.HasPrefix(line, "module ") {
-			return strings.TrimPrefix(line, "module "), nil
+	return "", false
+}
+
+// nestedModules keeps track of the modules nested in the sub-directories of a path.
+type nestedModules struct {
+	fsys     fileSystem
+	basePath string
+	// modules are keyed by the relative directories and nil for directories with no go.mod file.
+	modules map[string]*moduleFile
+}
+
+func newNestedModules(fsys fileSystem, basePath string) *nestedModules {
+	return &nestedModules{
+		fsys:     fsys,
+		basePath: basePath,
+		modules:  make(map[string]*moduleFile),
+	}
+}
+
+// lookup finds the nearest nested module containing a relative directory.
+// It also returns the directory relative to the nested module.
+// If the directory is not part of any nested module, it returns a nil module.
+func (n *nestedModules) lookup(relPath string) (*moduleFile, string, error) {
+	for dir := relPath; dir != "."; dir = filepath.Dir(dir) {
+		mf, ok := n.modules[dir]
+		if !ok {
+			var err error
+			if mf, err = n.read(dir); err != nil {
+				return nil, "", err
+			}
+			n.modules[dir] = mf
+		}
+
+		if mf != nil {
+			rel, err := filepath.Rel(dir, relPath)
+			if err != nil {
+				return nil, "", err
+			}
+			return mf, rel, nil
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	return nil, relPath, nil
+}
+
+// read parses the go.mod file in a relative directory if there is one.
+func (n *nestedModules) read(relPath string) (*moduleFile, error) {
+	dir := filepath.Join(n.basePath, relPath)
+	filename := filepath.Join(dir, "go.mod")
+
+	data, err := n.fsys.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	absDir, err := n.fsys.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModuleFile(absDir, filename, data)
+}
+
+// getModuleName returns the name of go module from a given path.
+func getModuleName(path string) (string, error) {
+	_, _, data, err := findModuleFile(osFileSystem{}, path)
+	if err != nil {
+		return "", err
+	}
+
+	return parseModuleName(data)
+}
+
+// derivedModuleName derives a module name for a path that is not part of any module.
+// For GOPATH-style paths (.../src/...), it is the path relative to the last src directory.
+// Otherwise, it is the name of the directory.
+func derivedModuleName(fsys fileSystem, path string) (string, error) {
+	absPath, err := fsys.Abs(path)
+	if err != nil {
 		return "", err
 	}
 
-	return "", errors.New("invalid go.mod file: no module name found")
+	parts := strings.Split(filepath.ToSlash(absPath), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "src" && i < len(parts)-1 {
+			return strings.Join(parts[i+1:], "/"), nil
+		}
+	}
+
+	return filepath.Base(absPath), nil
 }
 
 type visitFunc func(baseDir, relDir string) error
 
+// visitOptions configure how packages are traversed.
+type visitOptions struct {
+	// IncludeSubs enables visiting all sub-packages recursively.
+	IncludeSubs bool
+	// FollowSymlinks enables following symbolic links to directories.
+	// The real path of every visited directory is tracked to break cycles.
+	FollowSymlinks bool
+	// IncludeVendor enables visiting packages under vendor directories.
+	IncludeVendor bool
+	// MaxDepth limits the depth of sub-packages relative to the path (0 means unlimited).
+	MaxDepth int
+}
+
+// packageWalker keeps track of the state for traversing packages.
+type packageWalker struct {
+	visitOptions
+	fsys     fileSystem
+	basePath string
+	visit    visitFunc
+	// visited is nil if symbolic links should not be followed.
+	visited map[string]bool
+}
+
 // visitPackages traverses all packages from a given path.
-func visitPackages(includeSubs bool, path string, visit visitFunc) error {
+func visitPackages(fsys fileSystem, path string, opts visitOptions, visit visitFunc) error {
 	// Verify the path
-	info, err := os.Stat(path)
+	info, err := fsys.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -61,28 +266,63 @@ func visitPackages(includeSubs bool, path string, visit visitFunc) error {
 		return fmt.Errorf("%q is not a directory", path)
 	}
 
-	return visitPackagesRecursively(includeSubs, path, ".", visit)
+	w := &packageWalker{
+		visitOptions: opts,
+		fsys:         fsys,
+		basePath:     path,
+		visit:        visit,
+	}
+
+	if opts.FollowSymlinks {
+		realPath, err := fsys.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		w.visited = map[string]bool{realPath: true}
+	}
+
+	return w.walk(".", 0)
 }
 
-func visitPackagesRecursively(includeSubs bool, basePath, relPath string, visit visitFunc) error {
+// walk visits a package and all of its sub-packages.
+func (w *packageWalker) walk(relPath string, depth int) error {
 	// First, visit the current package
-	if err := visit(basePath, relPath); err != nil {
+	if err := w.visit(w.basePath, relPath); err != nil {
 		return err
 	}
 
 	// Then, visit all packages inside the current package
-	if includeSubs {
-		files, err := os.ReadDir(filepath.Join(basePath, relPath))
+	if w.IncludeSubs && (w.MaxDepth == 0 || depth < w.MaxDepth) {
+		files, err := w.fsys.ReadDir(filepath.Join(w.basePath, relPath))
 		if err != nil {
 			return err
 		}
 
 		for _, file := range files {
-			if file.IsDir() && isPackageDir(file.Name()) {
-				subRelPath := filepath.Join(relPath, file.Name())
-				if err := visitPackagesRecursively(includeSubs, basePath, subRelPath, visit); err != nil {
+			isSymlink := file.Type()&os.ModeSymlink != 0
+			if !isPackageDir(file.Name(), w.IncludeVendor) || (!file.IsDir() && !isSymlink) {
+				continue
+			}
+
+			// Skip symbolic links unless they should be followed
+			if isSymlink && w.visited == nil {
+				continue
+			}
+
+			subRelPath := filepath.Join(relPath, file.Name())
+
+			if w.visited != nil {
+				ok, err := w.markVisited(filepath.Join(w.basePath, subRelPath))
+				if err != nil {
 					return err
 				}
+				if !ok {
+					continue
+				}
+			}
+
+			if err := w.walk(subRelPath, depth+1); err != nil {
+				return err
 			}
 		}
 	}
@@ -90,13 +330,58 @@ func visitPackagesRecursively(includeSubs bool, basePath, relPath string, visit
 	return nil
 }
 
+// markVisited resolves a path to its real path and marks it as visited.
+// It returns false if the path is not a directory or it has been visited before.
+func (w *packageWalker) markVisited(path string) (bool, error) {
+	realPath, err := w.fsys.EvalSymlinks(path)
+	if err != nil {
+		// Ignore dangling symbolic links
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	info, err := w.fsys.Stat(realPath)
+	if err != nil {
+		return false, err
+	}
+
+	if !info.IsDir() || w.visited[realPath] {
+		return false, nil
+	}
+
+	w.visited[realPath] = true
+
+	return true, nil
+}
+
 // This helper function determines if a directory is a package directory and should be further traversed.
-func isPackageDir(name string) bool {
+func isPackageDir(name string, includeVendor bool) bool {
 	// Ignore directories starting with a dot (.git, .github, .build, etc)
 	startsWithDot := strings.HasPrefix(name, ".")
 
 	// Ignore build directories
 	isBuildDir := name == "bin" || name == "build"
 
-	return !startsWithDot && !isBuildDir
+	// Ignore vendor directories unless vendored packages should be visited
+	isVendorDir := name == "vendor" && !includeVendor
+
+	return !startsWithDot && !isBuildDir && !isVendorDir
+}
+
+// vendoredImportPath returns the import path of a package under a vendor directory.
+// The import path is derived from the directory structure under the last vendor directory (e.g. vendor/github.com/octocat/test).
+func vendoredImportPath(relPath string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "vendor" {
+			if i == len(parts)-1 {
+				return "", false
+			}
+			return strings.Join(parts[i+1:], "/"), true
+		}
+	}
+
+	return "", false
 }
@@ -2,46 +2,259 @@ package parser
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestGetModuleName(t *testing.T) {
+func TestReadModuleFile(t *testing.T) {
 	tests := []struct {
 		name           string
 		path           string
 		expectedModule string
 		expectedError  string
+		expectedIs     error
 	}{
 		{
 			name:          "NoModFile",
 			path:          "/opt",
-			expectedError: "stat /opt/go.mod: no such file or directory",
+			expectedError: "/opt: go.mod not found",
+			expectedIs:    ErrModuleNotFound,
 		},
 		{
 			name:          "InvalidModule",
 			path:          "./test/invalid_module",
 			expectedError: "invalid go.mod file: no module name found",
+			expectedIs:    ErrInvalidModule,
 		},
 		{
 			name:           "Success",
 			path:           "./test/valid/lookup",
 			expectedModule: "github.com/octocat/test",
 		},
+		{
+			name:           "Success_QuotedModule",
+			path:           "./test/quoted_module",
+			expectedModule: "github.com/octocat/quoted",
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			module, err := getModuleName(tc.path)
+			mf, err := readModuleFile(osFileSystem{}, tc.path)
+			module, nameErr := getModuleName(tc.path)
 
 			if tc.expectedError == "" {
 				assert.NoError(t, err)
+				assert.NoError(t, nameErr)
+				assert.Equal(t, tc.expectedModule, mf.Name())
 				assert.Equal(t, tc.expectedModule, module)
 			} else {
+				assert.Nil(t, mf)
 				assert.Empty(t, module)
 				assert.EqualError(t, err, tc.expectedError)
+				assert.EqualError(t, nameErr, tc.expectedError)
+				assert.ErrorIs(t, err, tc.expectedIs)
+			}
+		})
+	}
+}
+
+func TestParseModuleName(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		expectedModule string
+		expectedError  string
+	}{
+		{
+			name:          "Empty",
+			content:       "",
+			expectedError: "invalid go.mod file: no module name found",
+		},
+		{
+			name:          "NoModule",
+			content:       "go 1.21\n\nrequire github.com/octocat/lookup v1.0.0\n",
+			expectedError: "invalid go.mod file: no module name found",
+		},
+		{
+			name:          "Invalid",
+			content:       "module github.com/octocat/test v1\n",
+			expectedError: "invalid go.mod file: go.mod:1: usage: module module/path",
+		},
+		{
+			name:           "Module",
+			content:        "module github.com/octocat/test\n\ngo 1.21\n",
+			expectedModule: "github.com/octocat/test",
+		},
+		{
+			name:           "Comments",
+			content:        "// Deprecated: use v2.\nmodule github.com/octocat/test // main module\n",
+			expectedModule: "github.com/octocat/test",
+		},
+		{
+			name:           "Quoted",
+			content:        "module \"github.com/octocat/quoted\"\n",
+			expectedModule: "github.com/octocat/quoted",
+		},
+		{
+			name:           "Block",
+			content:        "module (\n\tgithub.com/octocat/block\n)\n",
+			expectedModule: "github.com/octocat/block",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			module, err := parseModuleName([]byte(tc.content))
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedModule, module)
+			} else {
+				assert.Empty(t, module)
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func FuzzParseModuleName(f *testing.F) {
+	f.Add([]byte("module github.com/octocat/test\n"))
+	f.Add([]byte("module \"github.com/octocat/quoted\" // comment\n"))
+	f.Add([]byte("module (\n\tgithub.com/octocat/block\n)\n"))
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		module, err := parseModuleName(content)
+		if err != nil {
+			assert.Empty(t, module)
+			assert.ErrorIs(t, err, ErrInvalidModule)
+		} else {
+			assert.NotEmpty(t, module)
+		}
+	})
+}
+
+func TestModuleFile_ReplacedImportPath(t *testing.T) {
+	mf, err := readModuleFile(osFileSystem{}, "./test/replace")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		dir                string
+		expectedImportPath string
+		expectedOK         bool
+	}{
+		{
+			name:       "NotReplaced",
+			dir:        ".",
+			expectedOK: false,
+		},
+		{
+			name:               "Replaced",
+			dir:                "local",
+			expectedImportPath: "github.com/octocat/local",
+			expectedOK:         true,
+		},
+		{
+			name:               "Replaced_SubPackage",
+			dir:                "local/sub",
+			expectedImportPath: "github.com/octocat/local/sub",
+			expectedOK:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			importPath, ok := mf.replacedImportPath(filepath.Join(mf.Dir, tc.dir))
+
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedImportPath, importPath)
+		})
+	}
+}
+
+func TestNestedModules_Lookup(t *testing.T) {
+	n := newNestedModules(osFileSystem{}, "./test/nested")
+
+	tests := []struct {
+		name            string
+		relPath         string
+		expectedModule  string
+		expectedRelPath string
+	}{
+		{
+			name:            "Root",
+			relPath:         ".",
+			expectedModule:  "",
+			expectedRelPath: ".",
+		},
+		{
+			name:            "NotNested",
+			relPath:         "internal",
+			expectedModule:  "",
+			expectedRelPath: "internal",
+		},
+		{
+			name:            "Nested",
+			relPath:         "tools",
+			expectedModule:  "github.com/octocat/tools",
+			expectedRelPath: ".",
+		},
+		{
+			name:            "Nested_SubPackage",
+			relPath:         filepath.Join("tools", "gen"),
+			expectedModule:  "github.com/octocat/tools",
+			expectedRelPath: "gen",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mf, relPath, err := n.lookup(tc.relPath)
+			assert.NoError(t, err)
+
+			if tc.expectedModule == "" {
+				assert.Nil(t, mf)
+			} else {
+				assert.Equal(t, tc.expectedModule, mf.Name())
 			}
+			assert.Equal(t, tc.expectedRelPath, relPath)
+		})
+	}
+}
+
+func TestDerivedModuleName(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedModule string
+	}{
+		{
+			name:           "GOPATH",
+			path:           "/home/octocat/go/src/github.com/octocat/test",
+			expectedModule: "github.com/octocat/test",
+		},
+		{
+			name:           "GOROOT",
+			path:           "/usr/local/go/src/net/http",
+			expectedModule: "net/http",
+		},
+		{
+			name:           "Directory",
+			path:           "/opt/lookup",
+			expectedModule: "lookup",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			module, err := derivedModuleName(osFileSystem{}, tc.path)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedModule, module)
 		})
 	}
 }
@@ -113,7 +326,7 @@ func TestVisitPackages(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := visitPackages(tc.includeSubs, tc.path, tc.visit)
+			err := visitPackages(osFileSystem{}, tc.path, visitOptions{IncludeSubs: tc.includeSubs}, tc.visit)
 
 			if tc.expectedError == "" {
 				assert.NoError(t, err)
@@ -123,3 +336,154 @@ func TestVisitPackages(t *testing.T) {
 		})
 	}
 }
+
+func TestVisitPackages_MaxDepth(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxDepth         int
+		expectedRelPaths []string
+	}{
+		{
+			name:             "Unlimited",
+			maxDepth:         0,
+			expectedRelPaths: []string{".", "internal", "tools", filepath.Join("tools", "gen")},
+		},
+		{
+			name:             "OneLevel",
+			maxDepth:         1,
+			expectedRelPaths: []string{".", "internal", "tools"},
+		},
+		{
+			name:             "Deep",
+			maxDepth:         2,
+			expectedRelPaths: []string{".", "internal", "tools", filepath.Join("tools", "gen")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			relPaths := []string{}
+			opts := visitOptions{
+				IncludeSubs: true,
+				MaxDepth:    tc.maxDepth,
+			}
+
+			err := visitPackages(osFileSystem{}, "./test/nested", opts, func(_, relPath string) error {
+				relPaths = append(relPaths, relPath)
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRelPaths, relPaths)
+		})
+	}
+}
+
+func TestVisitPackages_Symlinks(t *testing.T) {
+	root, external := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "foo", "bar"), 0755))
+	assert.NoError(t, os.Symlink(root, filepath.Join(root, "foo", "bar", "cycle")))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "foo"), filepath.Join(root, "link")))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "dangling")))
+	assert.NoError(t, os.Symlink(external, filepath.Join(root, "external")))
+
+	tests := []struct {
+		name             string
+		followSymlinks   bool
+		expectedRelPaths []string
+	}{
+		{
+			name:             "SkipSymlinks",
+			followSymlinks:   false,
+			expectedRelPaths: []string{".", "foo", "foo/bar"},
+		},
+		{
+			name:             "FollowSymlinks",
+			followSymlinks:   true,
+			expectedRelPaths: []string{".", "external", "foo", "foo/bar"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			relPaths := []string{}
+			opts := visitOptions{
+				IncludeSubs:    true,
+				FollowSymlinks: tc.followSymlinks,
+			}
+
+			err := visitPackages(osFileSystem{}, root, opts, func(_, relPath string) error {
+				relPaths = append(relPaths, relPath)
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRelPaths, relPaths)
+		})
+	}
+}
+
+func TestIsPackageDir(t *testing.T) {
+	tests := []struct {
+		name                 string
+		dir                  string
+		includeVendor        bool
+		expectedIsPackageDir bool
+	}{
+		{"Hidden", ".git", false, false},
+		{"Build", "build", false, false},
+		{"Bin", "bin", false, false},
+		{"Vendor", "vendor", false, false},
+		{"Vendor_Included", "vendor", true, true},
+		{"Package", "lookup", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isPackageDir := isPackageDir(tc.dir, tc.includeVendor)
+
+			assert.Equal(t, tc.expectedIsPackageDir, isPackageDir)
+		})
+	}
+}
+
+func TestVendoredImportPath(t *testing.T) {
+	tests := []struct {
+		name               string
+		relPath            string
+		expectedImportPath string
+		expectedOK         bool
+	}{
+		{
+			name:       "NotVendored",
+			relPath:    "internal/lookup",
+			expectedOK: false,
+		},
+		{
+			name:       "VendorDir",
+			relPath:    "vendor",
+			expectedOK: false,
+		},
+		{
+			name:               "Vendored",
+			relPath:            "vendor/github.com/octocat/test/lookup",
+			expectedImportPath: "github.com/octocat/test/lookup",
+			expectedOK:         true,
+		},
+		{
+			name:               "NestedVendored",
+			relPath:            "vendor/github.com/octocat/test/vendor/golang.org/x/mod",
+			expectedImportPath: "golang.org/x/mod",
+			expectedOK:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			importPath, ok := vendoredImportPath(tc.relPath)
+
+			assert.Equal(t, tc.expectedImportPath, importPath)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	goast "go/ast"
+	gotoken "go/token"
+)
+
+// PanicError is returned when a consumer panics while panics are recovered.
+type PanicError struct {
+	Consumer string
+	// Position is the position of the node being processed.
+	// Only the file or directory name is available if the consumer was not processing any node.
+	Position gotoken.Position
+	Value    any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("consumer %q panicked on %s: %v", e.Consumer, e.Position, e.Value)
+}
+
+// recoverPanic recovers a consumer panic and converts it to an error.
+// Any other panic is propagated.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		pe, ok := r.(*PanicError)
+		if !ok {
+			panic(r)
+		}
+		*err = pe
+	}
+}
+
+// nodePosition returns the position of a node in a file.
+// If the position is not known, only the file name is returned.
+func nodePosition(f *File, node goast.Node) gotoken.Position {
+	if f.FileSet != nil && node != nil && !reflect.ValueOf(node).IsNil() && node.Pos().IsValid() {
+		return f.FileSet.Position(node.Pos())
+	}
+
+	return gotoken.Position{
+		Filename: filepath.Join(f.BaseDir, f.RelativeDir, f.Name),
+	}
+}
+
+// recoverConsumer returns a copy of a consumer whose callbacks convert panics into PanicError panics.
+// The PanicError panics are recovered by the parser and returned as errors.
+func recoverConsumer(c *Consumer) *Consumer {
+	wrapped := *c
+
+	guard := func(pos func() gotoken.Position) {
+		if r := recover(); r != nil {
+			if _, ok := r.(*PanicError); ok {
+				panic(r)
+			}
+
+			panic(&PanicError{
+				Consumer: c.Name,
+				Position: pos(),
+				Value:    r,
+			})
+		}
+	}
+
+	if c.Directory != nil {
+		wrapped.Directory = func(absDir, relDir string) {
+			defer guard(func() gotoken.Position { return gotoken.Position{Filename: absDir} })
+			c.Directory(absDir, relDir)
+		}
+	}
+
+	if c.Package != nil {
+		wrapped.Package = func(p *Package, name string) bool {
+			defer guard(func() gotoken.Position {
+				return gotoken.Position{Filename: filepath.Join(p.BaseDir, p.RelativeDir)}
+			})
+			return c.Package(p, name)
+		}
+	}
+
+	if c.FilePre != nil {
+		wrapped.FilePre = func(f *File, file *goast.File) bool {
+			defer guard(func() gotoken.Position { return nodePosition(f, file) })
+			return c.FilePre(f, file)
+		}
+	}
+
+	if c.GenDecl != nil {
+		wrapped.GenDecl = func(f *File, d *goast.GenDecl) bool {
+			defer guard(func() gotoken.Position { return nodePosition(f, d) })
+			return c.GenDecl(f, d)
+		}
+	}
+
+	if c.Import != nil {
+		wrapped.Import = func(f *File, spec *goast.ImportSpec) {
+			defer guard(func() gotoken.Position { return nodePosition(f, spec) })
+			c.Import(f, spec)
+		}
+	}
+
+	if c.Struct != nil {
+		wrapped.Struct = func(t *Type, st *goast.StructType) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, st) })
+			c.Struct(t, st)
+		}
+	}
+
+	if c.StructTag != nil {
+		wrapped.StructTag = func(t *Type, fieldName string, tag reflect.StructTag) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, nil) })
+			c.StructTag(t, fieldName, tag)
+		}
+	}
+
+	if c.Interface != nil {
+		wrapped.Interface = func(t *Type, it *goast.InterfaceType) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, it) })
+			c.Interface(t, it)
+		}
+	}
+
+	if c.FuncType != nil {
+		wrapped.FuncType = func(t *Type, ft *goast.FuncType) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, ft) })
+			c.FuncType(t, ft)
+		}
+	}
+
+	if c.Alias != nil {
+		wrapped.Alias = func(t *Type, expr goast.Expr) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, expr) })
+			c.Alias(t, expr)
+		}
+	}
+
+	if c.Named != nil {
+		wrapped.Named = func(t *Type, expr goast.Expr) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, expr) })
+			c.Named(t, expr)
+		}
+	}
+
+	if c.AnonStruct != nil {
+		wrapped.AnonStruct = func(t *Type, st *goast.StructType) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, st) })
+			c.AnonStruct(t, st)
+		}
+	}
+
+	if c.AnonInterface != nil {
+		wrapped.AnonInterface = func(t *Type, it *goast.InterfaceType) {
+			defer guard(func() gotoken.Position { return nodePosition(&t.File, it) })
+			c.AnonInterface(t, it)
+		}
+	}
+
+	if c.FuncDecl != nil {
+		wrapped.FuncDecl = func(f *Func, ft *goast.FuncType, body *goast.BlockStmt) {
+			defer guard(func() gotoken.Position { return nodePosition(&f.File, ft) })
+			c.FuncDecl(f, ft, body)
+		}
+	}
+
+	if c.Directive != nil {
+		wrapped.Directive = func(f *File, directive string, args []string, pos gotoken.Position) {
+			defer guard(func() gotoken.Position { return pos })
+			c.Directive(f, directive, args, pos)
+		}
+	}
+
+	if c.FilePost != nil {
+		wrapped.FilePost = func(f *File, file *goast.File) error {
+			defer guard(func() gotoken.Position { return nodePosition(f, file) })
+			return c.FilePost(f, file)
+		}
+	}
+
+	return &wrapped
+}
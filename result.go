@@ -0,0 +1,301 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+
+	goast "go/ast"
+)
+
+// Result aggregates the packages, types, and functions discovered during parsing.
+type Result struct {
+	packages []*resultPackage
+	// index maps package IDs to the aggregated packages.
+	index map[string]*resultPackage
+}
+
+type resultPackage struct {
+	Name       string        `json:"name"`
+	ImportPath string        `json:"importPath"`
+	Types      []*resultType `json:"types"`
+	Funcs      []*resultFunc `json:"funcs"`
+
+	pkg *Package
+	// hasMain determines whether or not the package declares a main function.
+	hasMain bool
+}
+
+type resultType struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	File string `json:"file"`
+
+	typ *Type
+	// expr is the type expression of the type (the aliased type for aliases and the underlying type otherwise).
+	expr goast.Expr
+}
+
+type resultFunc struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	RecvName  string `json:"recvName,omitempty"`
+	RecvType  string `json:"recvType,omitempty"`
+	Signature string `json:"signature"`
+
+	ft *goast.FuncType
+}
+
+// NewResultConsumer creates a new consumer that aggregates the parsed packages into a result.
+func NewResultConsumer() (*Consumer, *Result) {
+	r := new(Result)
+
+	c := &Consumer{
+		Name:      "result",
+		Package:   r.pkg,
+		FilePre:   func(*File, *goast.File) bool { return true },
+		Struct:    func(t *Type, st *goast.StructType) { r.addType(t, "struct", st) },
+		Interface: func(t *Type, it *goast.InterfaceType) { r.addType(t, "interface", it) },
+		FuncType:  func(t *Type, ft *goast.FuncType) { r.addType(t, "func", ft) },
+		Alias:     func(t *Type, expr goast.Expr) { r.addType(t, "alias", expr) },
+		Named:     func(t *Type, expr goast.Expr) { r.addType(t, "named", expr) },
+		FuncDecl:  r.funcDecl,
+	}
+
+	return c, r
+}
+
+// lookup returns the aggregated package matching a given package.
+func (r *Result) lookup(p *Package) *resultPackage {
+	if r.index == nil {
+		r.index = make(map[string]*resultPackage)
+	}
+
+	id := p.ID()
+	if rp, ok := r.index[id]; ok {
+		return rp
+	}
+
+	pkg := *p
+	rp := &resultPackage{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		Types:      []*resultType{},
+		Funcs:      []*resultFunc{},
+		pkg:        &pkg,
+	}
+	r.packages = append(r.packages, rp)
+	r.index[id] = rp
+
+	return rp
+}
+
+func (r *Result) pkg(p *Package, _ string) bool {
+	r.lookup(p)
+	return true
+}
+
+func (r *Result) addType(t *Type, kind string, expr goast.Expr) {
+	typ := *t
+	rt := &resultType{
+		Name: t.Name,
+		Kind: kind,
+		File: t.File.Name,
+		typ:  &typ,
+		expr: expr,
+	}
+
+	rp := r.lookup(&t.Package)
+	rp.Types = append(rp.Types, rt)
+}
+
+func (r *Result) funcDecl(f *Func, ft *goast.FuncType, _ *goast.BlockStmt) {
+	rf := &resultFunc{
+		Name:      f.Name,
+		File:      f.File.Name,
+		RecvName:  f.RecvName,
+		Signature: exprString(ft),
+		ft:        ft,
+	}
+
+	if f.RecvType != nil {
+		rf.RecvType = exprString(f.RecvType)
+	}
+
+	rp := r.lookup(&f.Package)
+	rp.Funcs = append(rp.Funcs, rf)
+
+	if isMainFunc(f) {
+		rp.hasMain = true
+	}
+}
+
+// isMainFunc determines whether or not a function is the entry point of a main package (func main()).
+func isMainFunc(f *Func) bool {
+	return f.Package.Name == "main" && f.Name == "main" && !f.IsMethod() && !f.IsTest &&
+		len(f.Params) == 0 && len(f.Results) == 0 && len(f.TypeParams) == 0
+}
+
+// MainPackages returns the main packages declaring a main function in the order they were parsed.
+func (r *Result) MainPackages() []*Package {
+	packages := []*Package{}
+	for _, rp := range r.packages {
+		if rp.hasMain {
+			packages = append(packages, rp.pkg)
+		}
+	}
+
+	return packages
+}
+
+// findType finds a type by its name in the packages with a given import path.
+func (r *Result) findType(name, pkg string) *resultType {
+	for _, rp := range r.packages {
+		if rp.ImportPath != pkg {
+			continue
+		}
+
+		for _, rt := range rp.Types {
+			if rt.Name == name {
+				return rt
+			}
+		}
+	}
+
+	return nil
+}
+
+// findImportPath finds the import path of a package by its name.
+// It returns false if there is no such package or the name is ambiguous.
+func (r *Result) findImportPath(pkgName string) (string, bool) {
+	importPath := ""
+	for _, rp := range r.packages {
+		if rp.Name == pkgName {
+			if importPath != "" && importPath != rp.ImportPath {
+				return "", false
+			}
+			importPath = rp.ImportPath
+		}
+	}
+
+	return importPath, importPath != ""
+}
+
+// ResolveAlias follows the alias chain of a type in a package (import path) to the defined type it refers to.
+// If the type is not an alias, the type itself is returned.
+// Aliases to qualified types (e.g. lookup.Request) are resolved using the package names of the parsed packages.
+// It returns false if the type or any type in the chain is not found in the parsed packages,
+// or the chain ends in a type that is not declared (e.g. a builtin type).
+func (r *Result) ResolveAlias(name, pkg string) (*Type, bool) {
+	visited := make(map[string]bool)
+
+	for {
+		key := pkg + "." + name
+		if visited[key] {
+			// Invalid alias cycle
+			return nil, false
+		}
+		visited[key] = true
+
+		rt := r.findType(name, pkg)
+		if rt == nil {
+			return nil, false
+		}
+
+		if rt.Kind != "alias" {
+			return rt.typ, true
+		}
+
+		switch v := rt.expr.(type) {
+		case *goast.Ident:
+			name = v.Name
+		case *goast.SelectorExpr:
+			x, ok := v.X.(*goast.Ident)
+			if !ok {
+				return nil, false
+			}
+
+			if pkg, ok = r.findImportPath(x.Name); !ok {
+				return nil, false
+			}
+			name = v.Sel.Name
+		default:
+			return nil, false
+		}
+	}
+}
+
+// DuplicateTypes returns the exported type names declared in more than one package.
+// Each type name is mapped to the sorted import paths of the packages declaring it.
+// Only types are considered; see DuplicateMethods for methods.
+func (r *Result) DuplicateTypes() map[string][]string {
+	importPaths := make(map[string]map[string]bool)
+	for _, rp := range r.packages {
+		for _, rt := range rp.Types {
+			if !IsExported(rt.Name) {
+				continue
+			}
+
+			if _, ok := importPaths[rt.Name]; !ok {
+				importPaths[rt.Name] = make(map[string]bool)
+			}
+			importPaths[rt.Name][rp.ImportPath] = true
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for name, paths := range importPaths {
+		if len(paths) > 1 {
+			duplicates[name] = sortedKeys(paths)
+		}
+	}
+
+	return duplicates
+}
+
+// DuplicateMethods returns the exported methods declared in more than one package, separately from the types.
+// Methods are named by their receiver base type names (e.g. Service.Lookup for func (s *Service) Lookup()),
+// and each method name is mapped to the sorted import paths of the packages declaring it.
+func (r *Result) DuplicateMethods() map[string][]string {
+	importPaths := make(map[string]map[string]bool)
+	for _, rp := range r.packages {
+		for _, rf := range rp.Funcs {
+			if rf.RecvType == "" || !IsExported(rf.Name) {
+				continue
+			}
+
+			// Receiver base type name (e.g. *Cache[K, V] --> Cache)
+			recvType := strings.TrimPrefix(rf.RecvType, "*")
+			recvType, _, _ = strings.Cut(recvType, "[")
+
+			name := recvType + "." + rf.Name
+			if _, ok := importPaths[name]; !ok {
+				importPaths[name] = make(map[string]bool)
+			}
+			importPaths[name][rp.ImportPath] = true
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for name, paths := range importPaths {
+		if len(paths) > 1 {
+			duplicates[name] = sortedKeys(paths)
+		}
+	}
+
+	return duplicates
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// AST expressions such as receiver types and function signatures are rendered as Go source.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	packages := r.packages
+	if packages == nil {
+		packages = []*resultPackage{}
+	}
+
+	return json.Marshal(struct {
+		Packages []*resultPackage `json:"packages"`
+	}{
+		Packages: packages,
+	})
+}
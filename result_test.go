@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	goast "go/ast"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		packages     string
+		expectedJSON string
+	}{
+		{
+			name:         "Empty",
+			packages:     "",
+			expectedJSON: `{"packages":[]}`,
+		},
+		{
+			name:     "OK",
+			packages: "./test/valid/lookup",
+			expectedJSON: `{"packages":[{
+				"name": "lookup",
+				"importPath": "github.com/octocat/test/lookup",
+				"types": [
+					{"name": "ID", "kind": "alias", "file": "lookup.go"},
+					{"name": "Status", "kind": "named", "file": "lookup.go"},
+					{"name": "Request", "kind": "struct", "file": "lookup.go"},
+					{"name": "Response", "kind": "struct", "file": "lookup.go"},
+					{"name": "Func", "kind": "func", "file": "lookup.go"},
+					{"name": "Service", "kind": "interface", "file": "lookup.go"},
+					{"name": "service", "kind": "struct", "file": "lookup.go"}
+				],
+				"funcs": [
+					{"name": "New", "file": "lookup.go", "signature": "func() Service"},
+					{"name": "Lookup", "file": "lookup.go", "recvName": "s", "recvType": "*service", "signature": "func(ctx context.Context, req *Request) (*Response, error)"}
+				]
+			}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, r := NewResultConsumer()
+
+			if tc.packages != "" {
+				p := &parser{
+					ui:        ui.NewNop(),
+					consumers: []*Consumer{c},
+				}
+
+				err := p.Parse(tc.packages, ParseOptions{SkipTestFiles: true})
+				assert.NoError(t, err)
+			}
+
+			b, err := json.Marshal(r)
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expectedJSON, string(b))
+		})
+	}
+}
+
+func TestResult_DuplicateTypes(t *testing.T) {
+	r := &Result{
+		packages: []*resultPackage{
+			{
+				Name:       "lookup",
+				ImportPath: "github.com/octocat/test/lookup",
+				Types: []*resultType{
+					{Name: "Request", Kind: "struct"},
+					{Name: "Service", Kind: "interface"},
+					{Name: "service", Kind: "struct"},
+				},
+				Funcs: []*resultFunc{
+					{Name: "Lookup", RecvName: "s", RecvType: "*service"},
+				},
+			},
+			{
+				Name:       "search",
+				ImportPath: "github.com/octocat/test/search",
+				Types: []*resultType{
+					{Name: "Request", Kind: "struct"},
+					{Name: "Lookup", Kind: "func"},
+					{Name: "service", Kind: "struct"},
+				},
+				Funcs: []*resultFunc{
+					{Name: "Lookup", RecvName: "s", RecvType: "service"},
+					{Name: "Search", RecvName: "s", RecvType: "*service"},
+				},
+			},
+			{
+				Name:       "search_test",
+				ImportPath: "github.com/octocat/test/search",
+				Types: []*resultType{
+					{Name: "Service", Kind: "interface"},
+				},
+				Funcs: []*resultFunc{
+					{Name: "Search", RecvName: "s", RecvType: "*service"},
+				},
+			},
+			{
+				Name:       "cache",
+				ImportPath: "github.com/octocat/test/cache",
+				Funcs: []*resultFunc{
+					{Name: "Lookup", RecvName: "c", RecvType: "*service[K, V]"},
+					{Name: "lookup", RecvName: "c", RecvType: "*Request"},
+				},
+			},
+		},
+	}
+
+	duplicates := r.DuplicateTypes()
+
+	assert.Equal(t, map[string][]string{
+		"Request": {"github.com/octocat/test/lookup", "github.com/octocat/test/search"},
+		"Service": {"github.com/octocat/test/lookup", "github.com/octocat/test/search"},
+	}, duplicates)
+
+	duplicates = r.DuplicateMethods()
+
+	assert.Equal(t, map[string][]string{
+		"service.Lookup": {"github.com/octocat/test/cache", "github.com/octocat/test/lookup", "github.com/octocat/test/search"},
+	}, duplicates)
+}
+
+func TestResult_ResolveAlias(t *testing.T) {
+	lookupPkg := Package{Name: "lookup", ImportPath: "github.com/octocat/test/lookup"}
+	searchPkg := Package{Name: "search", ImportPath: "github.com/octocat/test/search"}
+
+	c, r := NewResultConsumer()
+
+	c.Struct(&Type{File: File{Package: lookupPkg}, Name: "Request"}, nil)
+	c.Alias(&Type{File: File{Package: lookupPkg}, Name: "Req", IsAlias: true}, goast.NewIdent("Request"))
+	c.Alias(&Type{File: File{Package: lookupPkg}, Name: "ID", IsAlias: true}, goast.NewIdent("string"))
+	c.Alias(&Type{File: File{Package: lookupPkg}, Name: "A", IsAlias: true}, goast.NewIdent("B"))
+	c.Alias(&Type{File: File{Package: lookupPkg}, Name: "B", IsAlias: true}, goast.NewIdent("A"))
+	c.Alias(&Type{File: File{Package: searchPkg}, Name: "Request", IsAlias: true}, &goast.SelectorExpr{
+		X:   goast.NewIdent("lookup"),
+		Sel: goast.NewIdent("Req"),
+	})
+	c.Alias(&Type{File: File{Package: searchPkg}, Name: "Query", IsAlias: true}, &goast.SelectorExpr{
+		X:   goast.NewIdent("query"),
+		Sel: goast.NewIdent("Query"),
+	})
+
+	tests := []struct {
+		name         string
+		typeName     string
+		pkg          string
+		expectedOK   bool
+		expectedPkg  string
+		expectedType string
+	}{
+		{
+			name:       "NotFound",
+			typeName:   "Response",
+			pkg:        "github.com/octocat/test/lookup",
+			expectedOK: false,
+		},
+		{
+			name:         "NotAlias",
+			typeName:     "Request",
+			pkg:          "github.com/octocat/test/lookup",
+			expectedOK:   true,
+			expectedPkg:  "github.com/octocat/test/lookup",
+			expectedType: "Request",
+		},
+		{
+			name:         "SamePackage",
+			typeName:     "Req",
+			pkg:          "github.com/octocat/test/lookup",
+			expectedOK:   true,
+			expectedPkg:  "github.com/octocat/test/lookup",
+			expectedType: "Request",
+		},
+		{
+			name:         "AnotherPackage",
+			typeName:     "Request",
+			pkg:          "github.com/octocat/test/search",
+			expectedOK:   true,
+			expectedPkg:  "github.com/octocat/test/lookup",
+			expectedType: "Request",
+		},
+		{
+			name:       "UnknownPackage",
+			typeName:   "Query",
+			pkg:        "github.com/octocat/test/search",
+			expectedOK: false,
+		},
+		{
+			name:       "Builtin",
+			typeName:   "ID",
+			pkg:        "github.com/octocat/test/lookup",
+			expectedOK: false,
+		},
+		{
+			name:       "Cycle",
+			typeName:   "A",
+			pkg:        "github.com/octocat/test/lookup",
+			expectedOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			typ, ok := r.ResolveAlias(tc.typeName, tc.pkg)
+
+			assert.Equal(t, tc.expectedOK, ok)
+			if tc.expectedOK {
+				assert.Equal(t, tc.expectedPkg, typ.ImportPath)
+				assert.Equal(t, tc.expectedType, typ.Name)
+				assert.False(t, typ.IsAlias)
+			} else {
+				assert.Nil(t, typ)
+			}
+		})
+	}
+}
+
+func TestResult_MainPackages(t *testing.T) {
+	t.Run("Parsed", func(t *testing.T) {
+		c, r := NewResultConsumer()
+		p := &parser{
+			ui:        ui.NewNop(),
+			consumers: []*Consumer{c},
+		}
+
+		err := p.Parse("./test/valid/...", ParseOptions{})
+		assert.NoError(t, err)
+
+		packages := r.MainPackages()
+
+		assert.Len(t, packages, 1)
+		assert.Equal(t, "main", packages[0].Name)
+		assert.Equal(t, "github.com/octocat/test", packages[0].ImportPath)
+	})
+
+	t.Run("Funcs", func(t *testing.T) {
+		mainPkg := Package{Name: "main", ImportPath: "github.com/octocat/test"}
+		toolPkg := Package{Name: "main", ImportPath: "github.com/octocat/test/tool"}
+		cmdPkg := Package{Name: "main", ImportPath: "github.com/octocat/test/cmd"}
+		libPkg := Package{Name: "lib", ImportPath: "github.com/octocat/test/lib"}
+
+		c, r := NewResultConsumer()
+		ft := &goast.FuncType{Params: &goast.FieldList{}}
+
+		c.FuncDecl(&Func{File: File{Package: mainPkg}, Name: "main", Type: ft}, ft, nil)
+		c.FuncDecl(&Func{File: File{Package: toolPkg}, Name: "main", RecvType: goast.NewIdent("tool"), Type: ft}, ft, nil)
+		c.FuncDecl(&Func{File: File{Package: toolPkg}, Name: "run", Type: ft}, ft, nil)
+		c.FuncDecl(&Func{File: File{Package: cmdPkg}, Name: "main", Params: []Param{{Name: "args"}}, Type: ft}, ft, nil)
+		c.FuncDecl(&Func{File: File{Package: libPkg}, Name: "main", Type: ft}, ft, nil)
+
+		packages := r.MainPackages()
+
+		assert.Equal(t, []*Package{&mainPkg}, packages)
+	})
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	goast "go/ast"
+)
+
+// Stats aggregates the number of declarations in each parsed package.
+type Stats struct {
+	Packages []*PackageStats
+	// index maps package IDs to the stats of the packages.
+	index map[string]*PackageStats
+}
+
+// PackageStats contains the number of declarations in a package.
+type PackageStats struct {
+	Name       string
+	ImportPath string
+	Structs    int
+	Interfaces int
+	Funcs      int
+	Methods    int
+	// Exported and Unexported count all types, functions, and methods.
+	Exported   int
+	Unexported int
+}
+
+// NewStatsConsumer creates a new consumer that counts the declarations in the parsed packages.
+func NewStatsConsumer() (*Consumer, *Stats) {
+	s := new(Stats)
+
+	c := &Consumer{
+		Name:    "stats",
+		Package: s.pkg,
+		FilePre: func(*File, *goast.File) bool { return true },
+		Struct: func(t *Type, _ *goast.StructType) {
+			s.addSymbol(&t.Package, t.Name).Structs++
+		},
+		Interface: func(t *Type, _ *goast.InterfaceType) {
+			s.addSymbol(&t.Package, t.Name).Interfaces++
+		},
+		FuncType: func(t *Type, _ *goast.FuncType) { s.addSymbol(&t.Package, t.Name) },
+		Alias:    func(t *Type, _ goast.Expr) { s.addSymbol(&t.Package, t.Name) },
+		Named:    func(t *Type, _ goast.Expr) { s.addSymbol(&t.Package, t.Name) },
+		FuncDecl: func(f *Func, _ *goast.FuncType, _ *goast.BlockStmt) {
+			ps := s.addSymbol(&f.Package, f.Name)
+			if f.IsMethod() {
+				ps.Methods++
+			} else {
+				ps.Funcs++
+			}
+		},
+	}
+
+	return c, s
+}
+
+// lookup returns the stats for a given package.
+func (s *Stats) lookup(p *Package) *PackageStats {
+	if s.index == nil {
+		s.index = make(map[string]*PackageStats)
+	}
+
+	id := p.ID()
+	if ps, ok := s.index[id]; ok {
+		return ps
+	}
+
+	ps := &PackageStats{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+	}
+	s.Packages = append(s.Packages, ps)
+	s.index[id] = ps
+
+	return ps
+}
+
+func (s *Stats) pkg(p *Package, _ string) bool {
+	s.lookup(p)
+	return true
+}
+
+func (s *Stats) addSymbol(p *Package, name string) *PackageStats {
+	ps := s.lookup(p)
+	if IsExported(name) {
+		ps.Exported++
+	} else {
+		ps.Unexported++
+	}
+
+	return ps
+}
+
+// String returns the stats as a table with one row per package.
+func (s *Stats) String() string {
+	b := new(strings.Builder)
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PACKAGE\tSTRUCTS\tINTERFACES\tFUNCS\tMETHODS\tEXPORTED\tUNEXPORTED")
+	for _, ps := range s.Packages {
+		fmt.Fprintf(w, "%s (%s)\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			ps.ImportPath, ps.Name, ps.Structs, ps.Interfaces, ps.Funcs, ps.Methods, ps.Exported, ps.Unexported)
+	}
+
+	_ = w.Flush()
+
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		packages       string
+		expectedStats  []*PackageStats
+		expectedString string
+	}{
+		{
+			name:           "Empty",
+			packages:       "",
+			expectedStats:  nil,
+			expectedString: "PACKAGE  STRUCTS  INTERFACES  FUNCS  METHODS  EXPORTED  UNEXPORTED\n",
+		},
+		{
+			name:     "OK",
+			packages: "./test/valid/...",
+			expectedStats: []*PackageStats{
+				{
+					Name:       "main",
+					ImportPath: "github.com/octocat/test",
+					Funcs:      1,
+					Unexported: 1,
+				},
+				{
+					Name:       "lookup",
+					ImportPath: "github.com/octocat/test/lookup",
+					Structs:    3,
+					Interfaces: 1,
+					Funcs:      1,
+					Methods:    1,
+					Exported:   8,
+					Unexported: 1,
+				},
+			},
+			expectedString: "PACKAGE                                  STRUCTS  INTERFACES  FUNCS  METHODS  EXPORTED  UNEXPORTED\n" +
+				"github.com/octocat/test (main)           0        0           1      0        0         1\n" +
+				"github.com/octocat/test/lookup (lookup)  3        1           1      1        8         1\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, s := NewStatsConsumer()
+
+			if tc.packages != "" {
+				p := &parser{
+					ui:        ui.NewNop(),
+					consumers: []*Consumer{c},
+				}
+
+				err := p.Parse(tc.packages, ParseOptions{SkipTestFiles: true})
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedStats, s.Packages)
+			assert.Equal(t, tc.expectedString, s.String())
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"context"
+
+	goast "go/ast"
+)
+
+// EventKind determines the kind of a parse event.
+type EventKind int
+
+const (
+	// PackageEvent is emitted for every parsed package.
+	PackageEvent EventKind = iota
+	// ImportEvent is emitted for every import declaration.
+	ImportEvent
+	// TypeEvent is emitted for every type declaration.
+	TypeEvent
+	// FuncEvent is emitted for every function and method declaration.
+	FuncEvent
+)
+
+// String implements the fmt.Stringer interface.
+func (k EventKind) String() string {
+	switch k {
+	case PackageEvent:
+		return "package"
+	case ImportEvent:
+		return "import"
+	case TypeEvent:
+		return "type"
+	case FuncEvent:
+		return "func"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a parse event emitted by Compiler.Stream.
+// Only the fields relevant to the kind of the event are set.
+type Event struct {
+	Kind EventKind
+	// Package is set for package events.
+	Package *Package
+	// File is set for import events.
+	File   *File
+	Import *goast.ImportSpec
+	// Type is set for type events and its type expression is available through Type.Expr.
+	Type *Type
+	// Func is set for func events, and FuncBody is nil for functions without a body.
+	Func     *Func
+	FuncBody *goast.BlockStmt
+}
+
+// Stream parses all Go source code files in a given path and emits the parse events on a channel.
+// If the path ends with "/...", all subdirectories will be considered too.
+//
+// The events channel is unbuffered, so parsing blocks until each event is received (backpressure).
+// The receiver must either drain the events channel until it is closed or cancel the context.
+// Once the context is canceled, no more events are sent and parsing stops after the file being processed.
+// After the events channel is closed, the error channel delivers the terminal error of parsing, if any, and is closed.
+// If parsing is stopped by canceling the context, the terminal error is the error of the context.
+// The consumers registered with the compiler are not called for a stream.
+func (c *Compiler) Stream(ctx context.Context, path string, opts ParseOptions) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	p := &parser{
+		ui:        c.parser.ui,
+		consumers: []*Consumer{streamConsumer(ctx, events)},
+		sources:   c.parser.sources,
+	}
+
+	go func() {
+		defer close(errs)
+
+		err := p.Parse(path, opts)
+		close(events)
+
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return events, errs
+}
+
+// streamConsumer creates a consumer that sends parse events on a channel until the context is canceled.
+// The values passed to the callbacks are copied, since the parser reuses them after the callbacks return.
+func streamConsumer(ctx context.Context, events chan<- Event) *Consumer {
+	send := func(e Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	typeEvent := func(t *Type) {
+		typ := *t
+		send(Event{Kind: TypeEvent, Type: &typ})
+	}
+
+	return &Consumer{
+		Name: "stream",
+		Package: func(p *Package, _ string) bool {
+			pkg := *p
+			send(Event{Kind: PackageEvent, Package: &pkg})
+			return true
+		},
+		FilePre: func(*File, *goast.File) bool {
+			return true
+		},
+		// Returning the error of a canceled context stops parsing
+		FilePost: func(*File, *goast.File) error {
+			return ctx.Err()
+		},
+		Import: func(f *File, spec *goast.ImportSpec) {
+			file := *f
+			send(Event{Kind: ImportEvent, File: &file, Import: spec})
+		},
+		Struct: func(t *Type, _ *goast.StructType) {
+			typeEvent(t)
+		},
+		Interface: func(t *Type, _ *goast.InterfaceType) {
+			typeEvent(t)
+		},
+		FuncType: func(t *Type, _ *goast.FuncType) {
+			typeEvent(t)
+		},
+		Alias: func(t *Type, _ goast.Expr) {
+			typeEvent(t)
+		},
+		Named: func(t *Type, _ goast.Expr) {
+			typeEvent(t)
+		},
+		AnonStruct: func(t *Type, _ *goast.StructType) {
+			typeEvent(t)
+		},
+		AnonInterface: func(t *Type, _ *goast.InterfaceType) {
+			typeEvent(t)
+		},
+		FuncDecl: func(f *Func, _ *goast.FuncType, body *goast.BlockStmt) {
+			fn := *f
+			send(Event{Kind: FuncEvent, Func: &fn, FuncBody: body})
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventKind_String(t *testing.T) {
+	assert.Equal(t, "package", PackageEvent.String())
+	assert.Equal(t, "import", ImportEvent.String())
+	assert.Equal(t, "type", TypeEvent.String())
+	assert.Equal(t, "func", FuncEvent.String())
+	assert.Equal(t, "unknown", EventKind(-1).String())
+}
+
+func TestCompiler_Stream(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		opts           ParseOptions
+		expectedEvents []string
+		expectedError  string
+	}{
+		{
+			name:          "PathNotExist",
+			path:          "./test/foo",
+			expectedError: "stat ./test/foo: no such file or directory",
+		},
+		{
+			name: "Success",
+			path: "./test/valid/...",
+			opts: ParseOptions{
+				SkipTestFiles: true,
+			},
+			expectedEvents: []string{
+				"package github.com/octocat/test",
+				`import "fmt"`,
+				"func github.com/octocat/test.main",
+				"package github.com/octocat/test/lookup",
+				`import "context"`,
+				"type ID",
+				"type Status",
+				"type Request",
+				"type Response",
+				"type Func",
+				"type Service",
+				"type service",
+				"func github.com/octocat/test/lookup.New",
+				"func github.com/octocat/test/lookup.(*service).Lookup",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// The consumers of the compiler are not called for streams
+			c := NewCompiler(ui.NewNop(), &Consumer{
+				Name: "tester",
+				Package: func(*Package, string) bool {
+					t.Fatal("unexpected call to the compiler consumer")
+					return false
+				},
+			})
+
+			events, errs := c.Stream(context.Background(), tc.path, tc.opts)
+
+			got := []string{}
+			for e := range events {
+				switch e.Kind {
+				case PackageEvent:
+					got = append(got, e.Kind.String()+" "+e.Package.ImportPath)
+				case ImportEvent:
+					got = append(got, e.Kind.String()+" "+e.Import.Path.Value)
+				case TypeEvent:
+					assert.NotNil(t, e.Type.Expr)
+					got = append(got, e.Kind.String()+" "+e.Type.Name)
+				case FuncEvent:
+					got = append(got, e.Kind.String()+" "+e.Func.FullName())
+				}
+			}
+
+			err := <-errs
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedEvents, got)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Empty(t, got)
+			}
+		})
+	}
+}
+
+func TestCompiler_Stream_Cancel(t *testing.T) {
+	c := NewCompiler(ui.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := c.Stream(ctx, "./test/valid/...", ParseOptions{})
+
+	// Stop reading after the first event
+	e := <-events
+	assert.Equal(t, PackageEvent, e.Kind)
+	cancel()
+
+	// The events channel is closed without draining it
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("parsing did not stop after canceling the context")
+	}
+}
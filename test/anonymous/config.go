@@ -0,0 +1,31 @@
+package anonymous
+
+import "io"
+
+// Config is the configuration.
+type Config struct {
+	Server struct {
+		Host string
+		Port int
+	}
+	Logger interface {
+		io.Writer
+	}
+}
+
+var defaults = struct {
+	Config Config
+}{}
+
+var options []struct {
+	Name string
+}
+
+type settings struct {
+	Cache map[struct{ Key string }]struct{ Value string }
+}
+
+// Handler handles requests.
+var Handler = func() {
+	_ = struct{ ID int }{}
+}
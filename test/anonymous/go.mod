@@ -0,0 +1,3 @@
+module github.com/octocat/anonymous
+
+go 1.17
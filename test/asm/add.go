@@ -0,0 +1,9 @@
+package asm
+
+// Add adds two integers and is implemented in assembly.
+func Add(a, b int) int
+
+// Sub subtracts two integers.
+func Sub(a, b int) int {
+	return a - b
+}
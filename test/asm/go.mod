@@ -0,0 +1,3 @@
+module github.com/octocat/asm
+
+go 1.17
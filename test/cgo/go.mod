@@ -0,0 +1,3 @@
+module github.com/octocat/cgo
+
+go 1.17
@@ -0,0 +1,8 @@
+package main
+
+// #include <stdlib.h>
+import "C"
+
+func random() int {
+	return int(C.random())
+}
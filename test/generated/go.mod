@@ -0,0 +1,3 @@
+module github.com/octocat/generated
+
+go 1.17
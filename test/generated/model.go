@@ -0,0 +1,6 @@
+package generated
+
+// Model is a hand-written model.
+type Model struct {
+	Name string
+}
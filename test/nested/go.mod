@@ -0,0 +1,3 @@
+module github.com/octocat/nested
+
+go 1.17
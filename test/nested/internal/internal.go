@@ -0,0 +1,4 @@
+package internal
+
+// Version is the version.
+const Version = "0.1.0"
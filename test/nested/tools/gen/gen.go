@@ -0,0 +1,4 @@
+package gen
+
+// Generate generates code.
+func Generate() {}
@@ -0,0 +1,3 @@
+module github.com/octocat/tools
+
+go 1.17
@@ -0,0 +1,3 @@
+package tools
+
+import _ "github.com/octocat/tools/gen"
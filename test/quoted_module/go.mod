@@ -0,0 +1,3 @@
+module "github.com/octocat/quoted" // quoted module path
+
+go 1.17
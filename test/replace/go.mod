@@ -0,0 +1,7 @@
+module github.com/octocat/replace
+
+go 1.17
+
+replace github.com/octocat/local => ./local
+
+replace github.com/octocat/remote => github.com/octocat/fork v0.1.0
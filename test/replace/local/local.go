@@ -0,0 +1,4 @@
+package local
+
+// Local is a locally replaced type.
+type Local struct{}
@@ -0,0 +1,4 @@
+package sub
+
+// Sub is a type in a sub-package of a locally replaced module.
+type Sub struct{}
@@ -1,10 +1,20 @@
+//go:generate echo lookup
+
+// Package lookup provides a service for looking up resources.
+// It is only used for testing.
 package lookup
 
 import "context"
 
+// ID is the lookup identifier.
+type ID = string
+
+// Status is the lookup status.
+type Status int
+
 // Request is the lookup request.
 type Request struct {
-	ID string
+	ID ID `json:"id"`
 }
 
 // Response is the lookup response.
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"sync"
+
+	"github.com/gardenbed/charm/ui"
+)
+
+// lockedUI serializes all calls to an underlying UI.
+// Compilations on the same compiler can run concurrently, so the UI passed to a compiler is not assumed to be concurrent-safe.
+// Files within a compilation are parsed sequentially for now; this is groundwork for parsing them in parallel.
+type lockedUI struct {
+	mu sync.Mutex
+	ui ui.UI
+}
+
+func newLockedUI(u ui.UI) ui.UI {
+	if l, ok := u.(*lockedUI); ok {
+		return l
+	}
+
+	return &lockedUI{ui: u}
+}
+
+func (u *lockedUI) Printf(format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Printf(format, a...)
+}
+
+func (u *lockedUI) GetLevel() ui.Level {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.ui.GetLevel()
+}
+
+func (u *lockedUI) SetLevel(l ui.Level) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.SetLevel(l)
+}
+
+func (u *lockedUI) Tracef(s ui.Style, format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Tracef(s, format, a...)
+}
+
+func (u *lockedUI) Debugf(s ui.Style, format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Debugf(s, format, a...)
+}
+
+func (u *lockedUI) Infof(s ui.Style, format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Infof(s, format, a...)
+}
+
+func (u *lockedUI) Warnf(s ui.Style, format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Warnf(s, format, a...)
+}
+
+func (u *lockedUI) Errorf(s ui.Style, format string, a ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.ui.Errorf(s, format, a...)
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+// bufferUI is a UI that is not concurrent-safe and writes each line in multiple steps.
+type bufferUI struct {
+	buf   bytes.Buffer
+	level ui.Level
+}
+
+func (u *bufferUI) write(prefix, format string, a ...any) {
+	u.buf.WriteString(prefix)
+	u.buf.WriteString(fmt.Sprintf(format, a...))
+	u.buf.WriteString("\n")
+}
+
+func (u *bufferUI) Printf(format string, a ...any) { u.write("[PRINT] ", format, a...) }
+func (u *bufferUI) GetLevel() ui.Level             { return u.level }
+func (u *bufferUI) SetLevel(l ui.Level)            { u.level = l }
+
+func (u *bufferUI) Tracef(_ ui.Style, format string, a ...any) { u.write("[TRACE] ", format, a...) }
+func (u *bufferUI) Debugf(_ ui.Style, format string, a ...any) { u.write("[DEBUG] ", format, a...) }
+func (u *bufferUI) Infof(_ ui.Style, format string, a ...any)  { u.write("[INFO] ", format, a...) }
+func (u *bufferUI) Warnf(_ ui.Style, format string, a ...any)  { u.write("[WARN] ", format, a...) }
+func (u *bufferUI) Errorf(_ ui.Style, format string, a ...any) { u.write("[ERROR] ", format, a...) }
+
+func TestNewLockedUI(t *testing.T) {
+	u := &bufferUI{}
+	l := newLockedUI(u)
+
+	assert.Equal(t, &lockedUI{ui: u}, l)
+	assert.Same(t, l, newLockedUI(l))
+}
+
+func TestLockedUI(t *testing.T) {
+	u := &bufferUI{}
+	l := newLockedUI(u)
+
+	l.SetLevel(ui.Trace)
+	assert.Equal(t, ui.Trace, l.GetLevel())
+
+	l.Printf("print %d", 1)
+	l.Tracef(ui.White, "trace %d", 2)
+	l.Debugf(ui.White, "debug %d", 3)
+	l.Infof(ui.White, "info %d", 4)
+	l.Warnf(ui.White, "warn %d", 5)
+	l.Errorf(ui.White, "error %d", 6)
+
+	assert.Equal(t, "[PRINT] print 1\n[TRACE] trace 2\n[DEBUG] debug 3\n[INFO] info 4\n[WARN] warn 5\n[ERROR] error 6\n", u.buf.String())
+}
+
+// TestCompiler_ConcurrentCompileUI verifies that UI calls from concurrent compilations are not interleaved.
+// Files within a single compilation are still parsed sequentially (there is no parallel parsing option yet),
+// so this only covers lockedUI as groundwork for parallel parsing, not parse concurrency itself.
+func TestCompiler_ConcurrentCompileUI(t *testing.T) {
+	u := &bufferUI{level: ui.Trace}
+	c := NewCompiler(u, &Consumer{
+		Name:    "tester",
+		Package: func(*Package, string) bool { return true },
+		FilePre: func(*File, *goast.File) bool { return true },
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Compile("./test/valid/...", ParseOptions{}))
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(u.buf.String(), "\n"), "\n")
+	assert.NotEmpty(t, lines)
+
+	prefixes := []string{"[PRINT] ", "[TRACE] ", "[DEBUG] ", "[INFO] ", "[WARN] ", "[ERROR] "}
+	for _, line := range lines {
+		// Each line has exactly one prefix at the beginning when the writes are not interleaved
+		count := 0
+		for _, prefix := range prefixes {
+			count += strings.Count(line, prefix)
+		}
+
+		assert.Equal(t, 1, count, "corrupted line: %q", line)
+		assert.Regexp(t, `^\[[A-Z]+\] `, line)
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+
+	goast "go/ast"
+	gotoken "go/token"
+)
+
+// Visitor is an interface-based alternative to Consumer for processing AST nodes.
+// BaseVisitor can be embedded for implementing only the methods of interest.
+type Visitor interface {
+	VisitDirectory(string, string)
+	VisitPackage(*Package, string) bool
+	VisitFilePre(*File, *goast.File) bool
+	VisitGenDecl(*File, *goast.GenDecl) bool
+	VisitImport(*File, *goast.ImportSpec)
+	VisitStruct(*Type, *goast.StructType)
+	VisitStructTag(*Type, string, reflect.StructTag)
+	VisitInterface(*Type, *goast.InterfaceType)
+	VisitFuncType(*Type, *goast.FuncType)
+	VisitAlias(*Type, goast.Expr)
+	VisitNamed(*Type, goast.Expr)
+	VisitAnonStruct(*Type, *goast.StructType)
+	VisitAnonInterface(*Type, *goast.InterfaceType)
+	VisitFuncDecl(*Func, *goast.FuncType, *goast.BlockStmt)
+	VisitDirective(*File, string, []string, gotoken.Position)
+	VisitFilePost(*File, *goast.File) error
+}
+
+// BaseVisitor provides default implementations for all methods of the Visitor interface.
+// By default, all packages and files are visited and no action is taken on AST nodes.
+type BaseVisitor struct{}
+
+// VisitDirectory implements the Visitor interface.
+func (BaseVisitor) VisitDirectory(string, string) {}
+
+// VisitPackage implements the Visitor interface.
+func (BaseVisitor) VisitPackage(*Package, string) bool { return true }
+
+// VisitFilePre implements the Visitor interface.
+func (BaseVisitor) VisitFilePre(*File, *goast.File) bool { return true }
+
+// VisitGenDecl implements the Visitor interface.
+func (BaseVisitor) VisitGenDecl(*File, *goast.GenDecl) bool { return true }
+
+// VisitImport implements the Visitor interface.
+func (BaseVisitor) VisitImport(*File, *goast.ImportSpec) {}
+
+// VisitStruct implements the Visitor interface.
+func (BaseVisitor) VisitStruct(*Type, *goast.StructType) {}
+
+// VisitStructTag implements the Visitor interface.
+func (BaseVisitor) VisitStructTag(*Type, string, reflect.StructTag) {}
+
+// VisitInterface implements the Visitor interface.
+func (BaseVisitor) VisitInterface(*Type, *goast.InterfaceType) {}
+
+// VisitFuncType implements the Visitor interface.
+func (BaseVisitor) VisitFuncType(*Type, *goast.FuncType) {}
+
+// VisitAlias implements the Visitor interface.
+func (BaseVisitor) VisitAlias(*Type, goast.Expr) {}
+
+// VisitNamed implements the Visitor interface.
+func (BaseVisitor) VisitNamed(*Type, goast.Expr) {}
+
+// VisitAnonStruct implements the Visitor interface.
+func (BaseVisitor) VisitAnonStruct(*Type, *goast.StructType) {}
+
+// VisitAnonInterface implements the Visitor interface.
+func (BaseVisitor) VisitAnonInterface(*Type, *goast.InterfaceType) {}
+
+// VisitFuncDecl implements the Visitor interface.
+func (BaseVisitor) VisitFuncDecl(*Func, *goast.FuncType, *goast.BlockStmt) {}
+
+// VisitDirective implements the Visitor interface.
+func (BaseVisitor) VisitDirective(*File, string, []string, gotoken.Position) {}
+
+// VisitFilePost implements the Visitor interface.
+func (BaseVisitor) VisitFilePost(*File, *goast.File) error { return nil }
+
+// visitorName returns the name of a visitor.
+// If the visitor implements a Name method, its result is used; otherwise, the name is derived from the visitor type.
+func visitorName(v Visitor) string {
+	if n, ok := v.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// visitorConsumer adapts a visitor to a consumer with a given name.
+func visitorConsumer(name string, v Visitor) *Consumer {
+	return &Consumer{
+		Name:          name,
+		Directory:     v.VisitDirectory,
+		Package:       v.VisitPackage,
+		FilePre:       v.VisitFilePre,
+		GenDecl:       v.VisitGenDecl,
+		Import:        v.VisitImport,
+		Struct:        v.VisitStruct,
+		StructTag:     v.VisitStructTag,
+		Interface:     v.VisitInterface,
+		FuncType:      v.VisitFuncType,
+		Alias:         v.VisitAlias,
+		Named:         v.VisitNamed,
+		AnonStruct:    v.VisitAnonStruct,
+		AnonInterface: v.VisitAnonInterface,
+		FuncDecl:      v.VisitFuncDecl,
+		Directive:     v.VisitDirective,
+		FilePost:      v.VisitFilePost,
+	}
+}
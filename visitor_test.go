@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"testing"
+
+	goast "go/ast"
+
+	"github.com/gardenbed/charm/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+type structVisitor struct {
+	BaseVisitor
+	names []string
+}
+
+func (v *structVisitor) VisitStruct(t *Type, _ *goast.StructType) {
+	v.names = append(v.names, t.Name)
+}
+
+type namedVisitor struct {
+	BaseVisitor
+	name string
+}
+
+func (v *namedVisitor) Name() string {
+	return v.name
+}
+
+func TestBaseVisitor(t *testing.T) {
+	v := BaseVisitor{}
+
+	assert.True(t, v.VisitPackage(nil, ""))
+	assert.True(t, v.VisitFilePre(nil, nil))
+	assert.NoError(t, v.VisitFilePost(nil, nil))
+}
+
+func TestVisitorConsumer(t *testing.T) {
+	v := new(structVisitor)
+	c := visitorConsumer("visitor", v)
+
+	assert.Equal(t, "visitor", c.Name)
+	assert.NotNil(t, c.Directory)
+	assert.NotNil(t, c.Package)
+	assert.NotNil(t, c.FilePre)
+	assert.NotNil(t, c.GenDecl)
+	assert.NotNil(t, c.Import)
+	assert.NotNil(t, c.Struct)
+	assert.NotNil(t, c.StructTag)
+	assert.NotNil(t, c.Interface)
+	assert.NotNil(t, c.FuncType)
+	assert.NotNil(t, c.Alias)
+	assert.NotNil(t, c.Named)
+	assert.NotNil(t, c.AnonStruct)
+	assert.NotNil(t, c.AnonInterface)
+	assert.NotNil(t, c.FuncDecl)
+	assert.NotNil(t, c.Directive)
+	assert.NotNil(t, c.FilePost)
+}
+
+func TestNewCompilerWithVisitor(t *testing.T) {
+	v := new(structVisitor)
+	c := NewCompilerWithVisitor(ui.NewNop(), v)
+
+	assert.NotNil(t, c)
+	assert.Len(t, c.parser.consumers, 1)
+
+	err := c.Compile("./test/valid/...", ParseOptions{SkipTestFiles: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Request", "Response", "service"}, v.names)
+}
+
+func TestVisitorName(t *testing.T) {
+	assert.Equal(t, "*parser.structVisitor", visitorName(new(structVisitor)))
+	assert.Equal(t, "custom", visitorName(&namedVisitor{name: "custom"}))
+}
+
+func TestNewCompilerWithVisitor_DuplicateNames(t *testing.T) {
+	v1, v2 := new(structVisitor), new(structVisitor)
+	c := NewCompilerWithVisitor(ui.NewNop(), v1, v2, &namedVisitor{name: "custom"})
+
+	assert.Len(t, c.parser.consumers, 3)
+	assert.Equal(t, "*parser.structVisitor", c.parser.consumers[0].Name)
+	assert.Equal(t, "*parser.structVisitor#2", c.parser.consumers[1].Name)
+	assert.Equal(t, "custom", c.parser.consumers[2].Name)
+
+	c.RemoveConsumer("*parser.structVisitor#2")
+
+	err := c.Compile("./test/valid/...", ParseOptions{SkipTestFiles: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Request", "Response", "service"}, v1.names)
+	assert.Empty(t, v2.names)
+}
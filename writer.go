@@ -5,13 +5,161 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/printer"
 	"go/token"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	goparser "go/parser"
 
 	"golang.org/x/tools/imports"
 )
 
+// LineEnding determines the line endings of written files.
+type LineEnding int
+
+const (
+	// LineEndingLF uses \n for line endings (default).
+	LineEndingLF LineEnding = iota
+	// LineEndingCRLF uses \r\n for line endings.
+	LineEndingCRLF
+	// LineEndingNative uses the line endings of the current platform (\r\n on Windows and \n otherwise).
+	LineEndingNative
+)
+
+// WriteOptions configure how Go source code files should be written.
+type WriteOptions struct {
+	// LineEnding determines the line endings of the written file.
+	// Files always end with exactly one trailing newline.
+	LineEnding LineEnding
+	// FormatMode, if set, is the go/printer mode used for the first formatting pass before goimports
+	// (e.g. printer.UseSpaces|printer.TabIndent|printer.SourcePos).
+	// By default, the first pass formats files the same way as gofmt.
+	FormatMode printer.Mode
+	// ImportGroups, if set, arranges the imports of the written file into groups.
+	ImportGroups *ImportGroups
+}
+
+// ImportGroups configure arranging imports into blank-line-separated groups:
+// standard library imports, third-party imports, and local imports.
+type ImportGroups struct {
+	// LocalPrefix is a comma-separated list of import path prefixes for local imports (e.g. github.com/octocat/test).
+	LocalPrefix string
+}
+
+var (
+	goimportsOptions = &imports.Options{
+		TabWidth:  8,
+		TabIndent: true,
+		Comments:  true,
+		Fragment:  true,
+	}
+
+	// localPrefixMu guards the global imports.LocalPrefix setting while goimports is running.
+	localPrefixMu sync.RWMutex
+)
+
+// runGoimports formats a Go source code file and fixes its imports using goimports.
+// If import groups are specified, the existing groups are merged first, so goimports arranges all imports again.
+func runGoimports(path string, src []byte, groups *ImportGroups) ([]byte, error) {
+	if groups == nil {
+		localPrefixMu.RLock()
+		defer localPrefixMu.RUnlock()
+
+		return imports.Process(path, src, goimportsOptions)
+	}
+
+	localPrefixMu.Lock()
+	defer localPrefixMu.Unlock()
+
+	localPrefix := imports.LocalPrefix
+	imports.LocalPrefix = groups.LocalPrefix
+	defer func() {
+		imports.LocalPrefix = localPrefix
+	}()
+
+	// The first pass merges all import declarations into one
+	b, err := imports.Process(path, src, goimportsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = mergeImportGroups(b); err != nil {
+		return nil, err
+	}
+
+	return imports.Process(path, b, goimportsOptions)
+}
+
+// mergeImportGroups removes the blank lines separating the import groups in the import declarations of a Go source code file.
+func mergeImportGroups(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", src, goparser.ImportsOnly|goparser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declarations are processed in reverse, so the offsets of the preceding ones remain valid
+	for i := len(file.Decls) - 1; i >= 0; i-- {
+		decl, ok := file.Decls[i].(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT || !decl.Lparen.IsValid() {
+			continue
+		}
+
+		start, end := fset.Position(decl.Lparen).Offset+1, fset.Position(decl.Rparen).Offset
+
+		lines := []string{}
+		for _, line := range strings.Split(string(src[start:end]), "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		block := "\n" + strings.Join(lines, "\n") + "\n"
+		src = append(src[:start:start], append([]byte(block), src[end:]...)...)
+	}
+
+	return src, nil
+}
+
+// formatNode formats a Go source code file using a printer mode or the gofmt style if the mode is zero.
+func formatNode(w io.Writer, fset *token.FileSet, file *ast.File, mode printer.Mode) error {
+	if mode == 0 {
+		return format.Node(w, fset, file)
+	}
+
+	cfg := &printer.Config{
+		Mode:     mode,
+		Tabwidth: 8,
+	}
+
+	return cfg.Fprint(w, fset, file)
+}
+
+// normalizeLineEndings converts all line endings to the requested ones and ensures exactly one trailing newline.
+func normalizeLineEndings(b []byte, le LineEnding) []byte {
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	b = append(bytes.TrimRight(b, "\r\n"), '\n')
+
+	if le == LineEndingCRLF || (le == LineEndingNative && runtime.GOOS == "windows") {
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte("\r\n"))
+	}
+
+	return b
+}
+
+// bufferPool reuses the buffers for formatting Go source code files.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
 func getDebugFilename(path string) string {
 	filename := filepath.Base(path)
 	ext := filepath.Ext(filename)
@@ -20,24 +168,109 @@ func getDebugFilename(path string) string {
 }
 
 // WriteFile formats and writes a Go source code file to disk.
+// The file is first written to a temporary file in the same directory and then renamed,
+// so an existing file is replaced atomically and never left partially written.
+// Comments of the file are preserved as long as the file set is the one the file was parsed with.
+// Files passed to consumers are always parsed with comments, so they can be modified and written back.
 func WriteFile(path string, fset *token.FileSet, file *ast.File) error {
-	buf := new(bytes.Buffer)
-	if err := format.Node(buf, fset, file); err != nil {
-		return fmt.Errorf("gofmt error: %s", err)
+	return WriteFileWithOptions(path, fset, file, WriteOptions{})
+}
+
+// WriteFileWithOptions formats and writes a Go source code file to disk using the provided options.
+func WriteFileWithOptions(path string, fset *token.FileSet, file *ast.File, opts WriteOptions) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		return WriteToWithOptions(w, fset, file, path, opts)
+	})
+}
+
+// FileToWrite is a Go source code file to be written by WriteFiles.
+type FileToWrite struct {
+	FileSet *token.FileSet
+	File    *ast.File
+	Options WriteOptions
+}
+
+// WriteFiles formats and writes multiple Go source code files to disk with all-or-nothing semantics.
+// The files are keyed by their paths and all of them are formatted in memory first, so nothing is written if any file fails to format.
+// Each file is then written atomically (see WriteFile). If writing a file fails,
+// the files already written are rolled back: new files are removed and existing files are restored.
+// Directories created for the files are not removed.
+func WriteFiles(files map[string]*FileToWrite) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
 
-	// Format the modified Go file
-	b, err := imports.Process(path, buf.Bytes(), &imports.Options{
-		TabWidth:  8,
-		TabIndent: true,
-		Comments:  true,
-		Fragment:  true,
+	contents := make(map[string][]byte, len(files))
+	for _, path := range paths {
+		f := files[path]
+
+		// Formatting is done in memory, so no debug log file is written for a file that fails to format
+		buf := new(bytes.Buffer)
+		if err := writeTo(buf, f.FileSet, f.File, path, f.Options, false); err != nil {
+			return err
+		}
+
+		contents[path] = buf.Bytes()
+	}
+
+	// The original contents of the existing files for rolling back
+	written := make([]string, 0, len(paths))
+	originals := make(map[string][]byte, len(paths))
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			original, err := os.ReadFile(path)
+			if err != nil {
+				rollback(written, originals)
+				return err
+			}
+			originals[path] = original
+		}
+
+		if err := writeBytes(path, contents[path]); err != nil {
+			rollback(written, originals)
+			return err
+		}
+
+		written = append(written, path)
+	}
+
+	return nil
+}
+
+// rollback restores the files written by WriteFiles in reverse order.
+// Rolling back is best-effort, since the original error is more relevant to the caller.
+func rollback(written []string, originals map[string][]byte) {
+	for i := len(written) - 1; i >= 0; i-- {
+		path := written[i]
+		if original, ok := originals[path]; ok {
+			_ = writeBytes(path, original)
+		} else {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// writeBytes writes the content of a file to disk atomically.
+func writeBytes(path string, b []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
 	})
+}
 
-	if err != nil {
-		// Write a log file for debugging purposes
-		_ = os.WriteFile(getDebugFilename(path), buf.Bytes(), 0644)
-		return fmt.Errorf("goimports error: %s", err)
+// writeAtomic writes a file to a temporary file in the same directory and then renames it.
+// The permissions of an existing file are preserved.
+func writeAtomic(path string, write func(io.Writer) error) error {
+	// Preserve the permissions of an existing file
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%q is a directory", path)
+		}
+		mode = info.Mode().Perm()
 	}
 
 	dir := filepath.Dir(path)
@@ -45,12 +278,77 @@ func WriteFile(path string, fset *token.FileSet, file *ast.File) error {
 		return err
 	}
 
-	f, err := os.Create(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
 	if err != nil {
 		return err
 	}
 
-	if _, err := f.Write(b); err != nil {
+	tmpPath := f.Name()
+
+	if err := writeTemp(f, mode, write); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// writeTemp writes a temporary file and closes it.
+func writeTemp(f *os.File, mode os.FileMode, write func(io.Writer) error) error {
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		return err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteTo formats and writes a Go source code file to a writer.
+// The path is only used for resolving imports and does not need to exist.
+func WriteTo(w io.Writer, fset *token.FileSet, file *ast.File, path string) error {
+	return WriteToWithOptions(w, fset, file, path, WriteOptions{})
+}
+
+// WriteToWithOptions formats and writes a Go source code file to a writer using the provided options.
+func WriteToWithOptions(w io.Writer, fset *token.FileSet, file *ast.File, path string, opts WriteOptions) error {
+	return writeTo(w, fset, file, path, opts, true)
+}
+
+// writeTo formats and writes a Go source code file to a writer.
+// If debugLog is true and goimports fails, the source code passed to goimports is written to a log file.
+func writeTo(w io.Writer, fset *token.FileSet, file *ast.File, path string, opts WriteOptions, debugLog bool) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+
+	if err := formatNode(buf, fset, file, opts.FormatMode); err != nil {
+		return fmt.Errorf("gofmt error: %s", err)
+	}
+
+	// Format the modified Go file
+	b, err := runGoimports(path, buf.Bytes(), opts.ImportGroups)
+
+	if err != nil {
+		// Write a log file for debugging purposes
+		if debugLog {
+			_ = os.WriteFile(getDebugFilename(path), buf.Bytes(), 0644)
+		}
+		return fmt.Errorf("goimports error: %s", err)
+	}
+
+	b = normalizeLineEndings(b, opts.LineEnding)
+
+	if _, err := w.Write(b); err != nil {
 		return err
 	}
 
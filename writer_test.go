@@ -1,45 +1,53 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"go/ast"
+	"go/printer"
 	"go/token"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	goparser "go/parser"
+
+	"github.com/gardenbed/charm/ui"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/imports"
 )
 
-func TestWriteFile(t *testing.T) {
-	mainFile := &ast.File{
-		Name: &ast.Ident{Name: "main"},
-		Decls: []ast.Decl{
-			&ast.GenDecl{
-				Tok: token.IMPORT,
-				Specs: []ast.Spec{
-					&ast.ImportSpec{
-						Path: &ast.BasicLit{
-							Value: `"fmt"`,
-						},
+var mainFile = &ast.File{
+	Name: &ast.Ident{Name: "main"},
+	Decls: []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.IMPORT,
+			Specs: []ast.Spec{
+				&ast.ImportSpec{
+					Path: &ast.BasicLit{
+						Value: `"fmt"`,
 					},
 				},
 			},
-			&ast.FuncDecl{
-				Name: &ast.Ident{Name: "main"},
-				Type: &ast.FuncType{
-					Params: &ast.FieldList{},
-				},
-				Body: &ast.BlockStmt{
-					List: []ast.Stmt{
-						&ast.ExprStmt{
-							X: &ast.CallExpr{
-								Fun: &ast.SelectorExpr{
-									X:   &ast.Ident{Name: "fmt"},
-									Sel: &ast.Ident{Name: "Println"},
-								},
-								Args: []ast.Expr{
-									&ast.BasicLit{
-										Value: `"Hello, World!"`,
-									},
+		},
+		&ast.FuncDecl{
+			Name: &ast.Ident{Name: "main"},
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{},
+			},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.ExprStmt{
+						X: &ast.CallExpr{
+							Fun: &ast.SelectorExpr{
+								X:   &ast.Ident{Name: "fmt"},
+								Sel: &ast.Ident{Name: "Println"},
+							},
+							Args: []ast.Expr{
+								&ast.BasicLit{
+									Value: `"Hello, World!"`,
 								},
 							},
 						},
@@ -47,8 +55,10 @@ func TestWriteFile(t *testing.T) {
 				},
 			},
 		},
-	}
+	},
+}
 
+func TestWriteFile(t *testing.T) {
 	tests := []struct {
 		name          string
 		path          string
@@ -61,7 +71,7 @@ func TestWriteFile(t *testing.T) {
 			path:          ".",
 			fset:          token.NewFileSet(),
 			file:          mainFile,
-			expectedError: "open .: is a directory",
+			expectedError: `"." is a directory`,
 		},
 		{
 			name: "InvalidFile",
@@ -97,3 +107,440 @@ func TestWriteFile(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteFile_PreserveMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"ReadWrite", 0600},
+		{"Executable", 0755},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.go")
+			assert.NoError(t, os.WriteFile(path, nil, tc.mode))
+			assert.NoError(t, os.Chmod(path, tc.mode))
+
+			err := WriteFile(path, token.NewFileSet(), mainFile)
+			assert.NoError(t, err)
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.mode, info.Mode().Perm())
+		})
+	}
+}
+
+func TestWriteFile_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	assert.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+
+	// Cleanup
+	defer os.Remove(getDebugFilename(path))
+
+	err := WriteFile(path, token.NewFileSet(), &ast.File{
+		Name: &ast.Ident{},
+	})
+	assert.Error(t, err)
+
+	// The existing file should be left intact and no temporary file should be left behind
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "package main\n", string(b))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestWriteTo(t *testing.T) {
+	tests := []struct {
+		name           string
+		w              io.Writer
+		file           *ast.File
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name: "InvalidFile",
+			w:    new(bytes.Buffer),
+			file: &ast.File{
+				Name: &ast.Ident{},
+			},
+			expectedError: "goimports error: main.go:1:9: expected 'IDENT', found 'EOF'",
+		},
+		{
+			name:          "WriterFails",
+			w:             errWriter{},
+			file:          mainFile,
+			expectedError: "write error",
+		},
+		{
+			name:           "Success",
+			w:              new(bytes.Buffer),
+			file:           mainFile,
+			expectedOutput: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello, World!\")\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WriteTo(tc.w, token.NewFileSet(), tc.file, "main.go")
+
+			// Cleanup
+			defer os.Remove(getDebugFilename("main.go"))
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedOutput, tc.w.(*bytes.Buffer).String())
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	tests := []struct {
+		name           string
+		b              []byte
+		le             LineEnding
+		expectedOutput string
+	}{
+		{
+			name:           "LF_NoTrailingNewline",
+			b:              []byte("package main\n\nfunc main() {}"),
+			le:             LineEndingLF,
+			expectedOutput: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:           "LF_MixedLineEndings",
+			b:              []byte("package main\r\n\nfunc main() {}\r\n\n\n"),
+			le:             LineEndingLF,
+			expectedOutput: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:           "CRLF",
+			b:              []byte("package main\n\nfunc main() {}\n"),
+			le:             LineEndingCRLF,
+			expectedOutput: "package main\r\n\r\nfunc main() {}\r\n",
+		},
+		{
+			name:           "CRLF_MixedLineEndings",
+			b:              []byte("package main\r\n\nfunc main() {}\r\n"),
+			le:             LineEndingCRLF,
+			expectedOutput: "package main\r\n\r\nfunc main() {}\r\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := normalizeLineEndings(tc.b, tc.le)
+
+			assert.Equal(t, tc.expectedOutput, string(b))
+		})
+	}
+}
+
+func TestWriteFileWithOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+
+	err := WriteFileWithOptions(path, token.NewFileSet(), mainFile, WriteOptions{
+		LineEnding: LineEndingCRLF,
+	})
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "package main\r\n\r\nimport \"fmt\"\r\n\r\nfunc main() {\r\n\tfmt.Println(\"Hello, World!\")\r\n}\r\n", string(b))
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	fset := token.NewFileSet()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := WriteTo(io.Discard, fset, mainFile, "main.go"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteToWithOptions_FormatMode(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "main.go", "package main\n\nfunc main() {\n}\n", goparser.ParseComments)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		mode        printer.Mode
+		expectedSrc string
+	}{
+		{
+			name:        "Default",
+			mode:        0,
+			expectedSrc: "package main\n\nfunc main() {\n}\n",
+		},
+		{
+			name:        "SourcePos",
+			mode:        printer.UseSpaces | printer.TabIndent | printer.SourcePos,
+			expectedSrc: "//line main.go:1\npackage main\n\nfunc main() {\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := WriteToWithOptions(buf, fset, file, "main.go", WriteOptions{FormatMode: tc.mode})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSrc, buf.String())
+		})
+	}
+}
+
+func TestWriteToWithOptions_ImportGroups(t *testing.T) {
+	src := `package main
+
+import (
+	"github.com/octocat/test/lookup"
+	"fmt"
+
+	"github.com/stretchr/testify/assert"
+	"os"
+)
+
+import "strings"
+
+var (
+	_ = lookup.New
+	_ = fmt.Println
+	_ = assert.True
+	_ = os.Exit
+	_ = strings.Cut
+)
+`
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "main.go", src, goparser.ParseComments)
+	assert.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	err = WriteToWithOptions(buf, fset, file, "main.go", WriteOptions{
+		ImportGroups: &ImportGroups{
+			LocalPrefix: "github.com/octocat/test",
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, imports.LocalPrefix)
+	assert.Equal(t, `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/octocat/test/lookup"
+)
+
+var (
+	_ = lookup.New
+	_ = fmt.Println
+	_ = assert.True
+	_ = os.Exit
+	_ = strings.Cut
+)
+`, buf.String())
+}
+
+func TestWriteFile_RoundTrip(t *testing.T) {
+	src := `// Package lookup provides lookup services.
+package lookup
+
+import (
+	"errors" // for sentinel errors
+	"strings"
+)
+
+/*
+ErrNotFound is returned when a name is not found.
+*/
+var ErrNotFound = errors.New("not found")
+
+// Service looks up names.
+type Service struct {
+	// names are the known names.
+	names []string // sorted
+}
+
+// Lookup finds a name.
+func (s *Service) Lookup(name string) error {
+	// Names are case-insensitive
+	for _, n := range s.names {
+		if strings.EqualFold(n, name) { // match
+			return nil
+		}
+	}
+
+	return ErrNotFound // not found
+}
+
+// trailing comment
+`
+
+	tests := []struct {
+		name           string
+		modify         func(*ast.File)
+		expectedOutput string
+	}{
+		{
+			name:           "Unchanged",
+			modify:         func(*ast.File) {},
+			expectedOutput: src,
+		},
+		{
+			name: "Renamed",
+			modify: func(file *ast.File) {
+				for _, decl := range file.Decls {
+					if f, ok := decl.(*ast.FuncDecl); ok && f.Name.Name == "Lookup" {
+						f.Name.Name = "Find"
+					}
+				}
+			},
+			expectedOutput: strings.Replace(src, "func (s *Service) Lookup(", "func (s *Service) Find(", 1),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/octocat/test\n"), 0644))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "lookup.go"), []byte(src), 0644))
+
+			path := filepath.Join(dir, "lookup.go")
+			c := NewCompiler(ui.NewNop(), &Consumer{
+				Name:    "writer",
+				Package: func(*Package, string) bool { return true },
+				FilePre: func(f *File, file *ast.File) bool {
+					tc.modify(file)
+					assert.NoError(t, WriteFile(path, f.FileSet, file))
+					return false
+				},
+			})
+
+			assert.NoError(t, c.Compile(dir, ParseOptions{}))
+
+			b, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, string(b))
+		})
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	invalidFile := &ast.File{
+		Name: &ast.Ident{},
+	}
+
+	tests := []struct {
+		name            string
+		files           func(dir string) map[string]*FileToWrite
+		expectedError   string
+		expectedEntries map[string]string
+	}{
+		{
+			name: "FormatFails",
+			files: func(dir string) map[string]*FileToWrite {
+				return map[string]*FileToWrite{
+					filepath.Join(dir, "a.go"): {FileSet: token.NewFileSet(), File: mainFile},
+					filepath.Join(dir, "b.go"): {FileSet: token.NewFileSet(), File: invalidFile},
+				}
+			},
+			expectedError: "goimports error: ",
+			expectedEntries: map[string]string{
+				"existing.go": "package main\n",
+			},
+		},
+		{
+			name: "WriteFails",
+			files: func(dir string) map[string]*FileToWrite {
+				return map[string]*FileToWrite{
+					filepath.Join(dir, "a.go"):           {FileSet: token.NewFileSet(), File: mainFile},
+					filepath.Join(dir, "existing.go"):    {FileSet: token.NewFileSet(), File: mainFile},
+					filepath.Join(dir, "sub", "main.go"): {FileSet: token.NewFileSet(), File: mainFile},
+					filepath.Join(dir, "x"):              {FileSet: token.NewFileSet(), File: mainFile},
+				}
+			},
+			expectedError: "is a directory",
+			expectedEntries: map[string]string{
+				"existing.go": "package main\n",
+			},
+		},
+		{
+			name: "Success",
+			files: func(dir string) map[string]*FileToWrite {
+				return map[string]*FileToWrite{
+					filepath.Join(dir, "existing.go"):    {FileSet: token.NewFileSet(), File: mainFile},
+					filepath.Join(dir, "sub", "main.go"): {FileSet: token.NewFileSet(), File: mainFile},
+				}
+			},
+			expectedEntries: map[string]string{
+				"existing.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello, World!\")\n}\n",
+				"sub/main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello, World!\")\n}\n",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "existing.go"), []byte("package main\n"), 0644))
+			assert.NoError(t, os.MkdirAll(filepath.Join(dir, "x"), 0755))
+
+			files := tc.files(dir)
+			err := WriteFiles(files)
+
+			// No debug log file is written for files that fail to format
+			for path := range files {
+				assert.NoFileExists(t, getDebugFilename(path))
+			}
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+
+			entries := map[string]string{}
+			err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				rel, _ := filepath.Rel(dir, path)
+				entries[filepath.ToSlash(rel)] = string(b)
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedEntries, entries)
+		})
+	}
+}